@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned when an Async queue cannot take any more requests.
+	ErrQueueFull = errors.New("http async queue full")
+
+	errAsyncClosed = errors.New("http async sender closed")
+)
+
+// An Async sends fire-and-forget requests in the background, through a fixed pool of workers.
+// Responses are discarded, and errors are passed to an optional callback.
+type Async struct {
+	cli     Client
+	onError func(error)
+	queue   chan asyncJob
+	workers sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type asyncJob struct {
+	ctx  context.Context
+	body []byte
+}
+
+// AsyncMake returns an Async that sends requests through cli, using the given number of workers, which is at least 1.
+// Up to queue requests may wait for a free worker, after which Send fails with ErrQueueFull.
+// onError is called with the error of each failed request, from worker goroutines; it may be nil.
+func AsyncMake(cli Client, workers, queue int, onError func(error)) *Async {
+	x := &Async{
+		cli:     cli,
+		onError: onError,
+		queue:   make(chan asyncJob, queue),
+	}
+	workers = max(workers, 1)
+	x.workers.Add(workers)
+	for range workers {
+		go x.work()
+	}
+	return x
+}
+
+// Close stops accepting new requests, and waits for queued ones to finish.
+func (x *Async) Close() error {
+	x.mu.Lock()
+	if !x.closed {
+		x.closed = true
+		close(x.queue)
+	}
+	x.mu.Unlock()
+
+	x.workers.Wait()
+	return nil
+}
+
+// Send queues a buffered exchange with body as request data, and returns without waiting for it.
+// The request is bound to ctx; if ctx is done before a worker picks it up, it is not sent at all.
+//
+// Ownership of body passes to the Async, so it must not be modified afterwards.
+// Returns ErrQueueFull, without blocking, if the queue is full.
+func (x *Async) Send(ctx context.Context, body []byte) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	if x.closed {
+		return errAsyncClosed
+	}
+	select {
+	case x.queue <- asyncJob{ctx, body}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// send performs a single queued exchange.
+func (x *Async) send(job asyncJob) error {
+	if err := context.Cause(job.ctx); err != nil {
+		return err
+	}
+
+	w, err := x.cli.WriterContext(job.ctx)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err := w.Write(job.body); err != nil {
+		return err
+	}
+	r, err := w.Reader()
+	if err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+func (x *Async) work() {
+	defer x.workers.Done()
+	for job := range x.queue {
+		if err := x.send(job); err != nil && x.onError != nil {
+			x.onError(err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AuthBearer returns a [Client.Auth] function that sets a bearer token obtained from token.
+// token is called for every request, so it may be used to refresh expiring tokens.
+func AuthBearer(token func() (string, error)) func(*http.Request) error {
+	return func(req *http.Request) error {
+		t, err := token()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("authorization", "Bearer "+t)
+		return nil
+	}
+}
+
+// ClientBearer returns a Client that authenticates with a static bearer token.
+// See [ClientMake].
+func ClientBearer(addr, token string, cli *http.Client) Client {
+	x := ClientMake(addr, cli)
+	x.Auth = AuthBearer(func() (string, error) {
+		return token, nil
+	})
+	return x
+}
+
+// AuthBasic returns a [Client.Auth] function that sets HTTP basic authentication credentials.
+func AuthBasic(user, pass string) func(*http.Request) error {
+	return func(req *http.Request) error {
+		req.SetBasicAuth(user, pass)
+		return nil
+	}
+}
+
+// ClientBasicAuth returns a Client that authenticates with HTTP basic authentication.
+// See [ClientMake].
+func ClientBasicAuth(addr, user, pass string, cli *http.Client) Client {
+	x := ClientMake(addr, cli)
+	x.Auth = AuthBasic(user, pass)
+	return x
+}
+
+// HandlerAuth wraps h to only accept requests that carry a valid bearer token, as reported by verify.
+// Requests with a missing or invalid token are answered with 401 Unauthorized, while verify errors result in 500 Internal Server Error.
+func HandlerAuth(h http.Handler, verify func(token string) (bool, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("authorization"), " ")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(scheme, "bearer") || token == "" {
+			unauthorized(w)
+			return
+		}
+
+		ok, err := verify(token)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			unauthorized(w)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("www-authenticate", "Bearer")
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// balancer distributes requests across a set of endpoints in round-robin fashion, temporarily skipping failing ones.
+// It is safe for concurrent use.
+type balancer struct {
+	next     atomic.Uint64
+	down     []atomic.Int64 // per endpoint; unix nanoseconds until which it is considered failing
+	cooldown time.Duration
+}
+
+func balancerMake(n int, cooldown time.Duration) *balancer {
+	return &balancer{
+		down:     make([]atomic.Int64, n),
+		cooldown: cooldown,
+	}
+}
+
+// mark records the outcome of a request to endpoint i.
+func (x *balancer) mark(i int, failed bool) {
+	if failed {
+		x.down[i].Store(time.Now().Add(x.cooldown).UnixNano())
+	} else {
+		x.down[i].Store(0)
+	}
+}
+
+// order returns the endpoint indexes to try, starting with the next one in turn.
+// Failing endpoints are moved to the end, so that they are only tried as a last resort.
+func (x *balancer) order() []int {
+	n := len(x.down)
+	if n == 0 {
+		return nil
+	}
+
+	var (
+		start = int((x.next.Add(1) - 1) % uint64(n))
+		now   = time.Now().UnixNano()
+		o     = make([]int, 0, n)
+		down  []int
+	)
+	for j := range n {
+		i := (start + j) % n
+		if x.down[i].Load() > now {
+			down = append(down, i)
+		} else {
+			o = append(o, i)
+		}
+	}
+	return append(o, down...)
+}
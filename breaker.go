@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of sending requests while a Breaker is open.
+var ErrCircuitOpen = errors.New("http circuit breaker open")
+
+// BreakerState is the state of a Breaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // requests are sent normally
+	BreakerOpen                         // requests fail immediately with ErrCircuitOpen
+	BreakerHalfOpen                     // a single probe request is allowed, in order to test recovery
+)
+
+func (x BreakerState) String() string {
+	switch x {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "invalid"
+}
+
+// A Breaker is a circuit breaker that stops requests to an upstream that is clearly failing.
+// Transport errors and 5xx responses count as failures, unless the request was canceled by its caller.
+//
+// It is safe for concurrent use, and may be shared between Clients.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	failures  int       // consecutive failures
+	opened    time.Time // when the breaker last opened
+	probing   bool      // a half-open probe is in flight
+}
+
+// BreakerMake returns a Breaker that opens after threshold consecutive failures, and half-opens after cooldown.
+func BreakerMake(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+}
+
+// State returns the current Breaker state.
+func (x *Breaker) State() BreakerState {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.update()
+	return x.state
+}
+
+// allow reports whether a request may be sent.
+// If it returns true, the outcome must be reported through record or abandon.
+func (x *Breaker) allow() bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.update()
+	switch x.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if x.probing {
+			return false
+		}
+		x.probing = true
+	}
+	return true
+}
+
+// record accounts for the outcome of an allowed request.
+func (x *Breaker) record(failed bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.probing = false
+	if !failed {
+		x.state = BreakerClosed
+		x.failures = 0
+		return
+	}
+
+	x.failures++
+	if x.state == BreakerHalfOpen || x.failures >= x.threshold {
+		x.state = BreakerOpen
+		x.opened = time.Now()
+	}
+}
+
+// abandon accounts for an allowed request that was canceled by its caller, which says nothing about the upstream.
+func (x *Breaker) abandon() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.probing = false
+}
+
+// update moves an open breaker to half-open, once the cooldown has passed.
+// The caller must hold the lock.
+func (x *Breaker) update() {
+	if x.state == BreakerOpen && time.Since(x.opened) >= x.cooldown {
+		x.state = BreakerHalfOpen
+	}
+}
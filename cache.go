@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blitz-frost/io"
+)
+
+// cacheVary lists the request headers that distinguish otherwise identical cached requests.
+var cacheVary = []string{"accept", "accept-encoding", "accept-language", "range"}
+
+// A Cache stores successful responses to GET requests in memory, for reuse by subsequent identical requests.
+// Entries expire after the Cache TTL, or the max-age requested by the server through the Cache-Control header.
+// Responses marked as no-store or no-cache are not stored.
+//
+// Requests are identified by URL, along with their Accept, Accept-Encoding, Accept-Language and Range headers.
+// In particular, credentials are not taken into account, so Clients acting on behalf of different users should not share a Cache.
+//
+// It is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int64                    // maximum total body size
+	size    int64                    // current total body size
+	lru     *list.List               // of *cacheEntry, most recently used first
+	m       map[string]*list.Element // by key
+}
+
+type cacheEntry struct {
+	key     string
+	status  int
+	text    string // full status text
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// CacheMake returns a Cache with a default entry lifetime of ttl, holding up to maxSize bytes of response bodies.
+// Least recently used entries are evicted to make room for new ones.
+func CacheMake(ttl time.Duration, maxSize int64) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		lru:     list.New(),
+		m:       make(map[string]*list.Element),
+	}
+}
+
+// capture wraps r so that its body is stored under key once fully read, if the response allows it.
+func (x *Cache) capture(key string, r *ClientReader) {
+	resp := r.resp
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	ttl, ok := x.lifetime(resp.Header)
+	if !ok {
+		return
+	}
+
+	e := &cacheEntry{
+		key:     key,
+		status:  resp.StatusCode,
+		text:    resp.Status,
+		header:  resp.Header.Clone(),
+		expires: time.Now().Add(ttl),
+	}
+	r.r = &captureReader{
+		r:   r.r,
+		max: x.maxSize,
+		done: func(body []byte) {
+			e.body = body
+			x.put(e)
+		},
+	}
+}
+
+// lifetime returns how long a response with the given header may be cached.
+func (x *Cache) lifetime(header http.Header) (time.Duration, bool) {
+	ttl := x.ttl
+	for _, directive := range strings.Split(header.Get("cache-control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store", directive == "no-cache":
+			return 0, false
+		case strings.HasPrefix(directive, "max-age="):
+			n, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil {
+				ttl = time.Duration(n) * time.Second
+			}
+		}
+	}
+	return ttl, ttl > 0
+}
+
+// put stores e, evicting old entries as needed.
+func (x *Cache) put(e *cacheEntry) {
+	size := int64(len(e.body))
+	if size > x.maxSize {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if elem, ok := x.m[e.key]; ok {
+		x.remove(elem)
+	}
+	for x.size+size > x.maxSize {
+		x.remove(x.lru.Back())
+	}
+
+	x.m[e.key] = x.lru.PushFront(e)
+	x.size += size
+}
+
+// reader returns a ClientReader over the response stored under key, if any.
+func (x *Cache) reader(key string) (*ClientReader, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	elem, ok := x.m[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		x.remove(elem)
+		return nil, false
+	}
+	x.lru.MoveToFront(elem)
+
+	body := io.BytesReader(e.body)
+	return &ClientReader{
+		r: &body,
+		resp: &http.Response{
+			Status:        e.text,
+			StatusCode:    e.status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        e.header.Clone(),
+			Body:          http.NoBody,
+			ContentLength: int64(len(e.body)),
+		},
+	}, true
+}
+
+// remove deletes a cache element. The caller must hold the lock.
+func (x *Cache) remove(elem *list.Element) {
+	e := x.lru.Remove(elem).(*cacheEntry)
+	delete(x.m, e.key)
+	x.size -= int64(len(e.body))
+}
+
+// cacheKey returns the Cache key of the exchange request.
+func (x *ClientWriter) cacheKey(p payload) (string, error) {
+	addr, err := addrMake(x.cli.addrs[0], p.path, p.query)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(addr)
+	for _, name := range cacheVary {
+		v := x.header.Get(name)
+		if v == "" {
+			v = x.cli.Header.Get(name)
+		}
+		if v == "" && name == "accept" {
+			v = x.cli.Accept
+		}
+		b.WriteString("\n" + v)
+	}
+	return b.String(), nil
+}
+
+// captureReader records a response body as it is read, passing it on to done once complete.
+// Bodies that exceed max, or fail to be read completely, are discarded.
+type captureReader struct {
+	r    io.Reader
+	buf  bytes.Buffer
+	max  int64             // non-positive for no limit
+	done func(body []byte) // nil once called or given up on
+}
+
+func (x *captureReader) Close() error {
+	return x.r.Close()
+}
+
+func (x *captureReader) Read(b []byte) (int, error) {
+	n, err := x.r.Read(b)
+	if x.done == nil {
+		return n, err
+	}
+
+	x.buf.Write(b[:n])
+	if x.max > 0 && int64(x.buf.Len()) > x.max {
+		x.done = nil
+		x.buf = bytes.Buffer{}
+	} else if err == io.EOF {
+		x.done(x.buf.Bytes())
+		x.done = nil
+	} else if err != nil {
+		x.done = nil
+	}
+	return n, err
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// originAny is the CORS wildcard origin.
+const originAny = "*"
+
+// CORS configures the acceptance of cross-origin requests.
+type CORS struct {
+	// Origins lists the allowed origins. The "*" wildcard allows any origin.
+	// If there is more than one, the request origin is reflected back when allowed, and responses are marked with a Vary header accordingly.
+	Origins []string
+
+	// Credentials allows requests that include credentials, such as cookies.
+	// Browsers refuse credentials in combination with a wildcard origin, so the two cannot be used together.
+	Credentials bool
+
+	Methods []string // methods allowed by preflight responses; defaults to POST
+	Headers []string // request headers allowed by preflight responses; defaults to Content-Type
+
+	// MaxAge is how long browsers may cache preflight results, with second precision.
+	// Non-positive values leave the browser default in place.
+	MaxAge time.Duration
+}
+
+// Wrap returns a handler that accepts CORS requests as configured, before forwarding them to h.
+// Disallowed requests are forwarded without CORS headers.
+//
+// Returns an error if Credentials is used together with the wildcard origin.
+func (x CORS) Wrap(h http.Handler) (http.Handler, error) {
+	var (
+		allow   func(*http.Request) string
+		reflect bool // the allowed origin depends on the request
+	)
+
+	allowed := make(map[string]struct{}, len(x.Origins))
+	for _, origin := range x.Origins {
+		allowed[origin] = struct{}{}
+	}
+
+	if _, ok := allowed[originAny]; ok {
+		if x.Credentials {
+			return nil, errors.New("CORS credentials cannot be used with wildcard origin")
+		}
+		allow = func(*http.Request) string {
+			return originAny
+		}
+	} else if len(x.Origins) == 1 {
+		origin := x.Origins[0]
+		allow = func(*http.Request) string {
+			return origin
+		}
+	} else {
+		reflect = true
+		allow = func(r *http.Request) string {
+			origin := r.Header.Get("origin")
+			if _, ok := allowed[origin]; !ok {
+				return ""
+			}
+			return origin
+		}
+	}
+
+	methods := http.MethodPost
+	if x.Methods != nil {
+		methods = strings.Join(x.Methods, ", ")
+	}
+	headers := "content-type"
+	if x.Headers != nil {
+		headers = strings.Join(x.Headers, ", ")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := allow(r)
+		if reflect {
+			// caches must not serve a response to a different origin, including ones without CORS headers
+			w.Header().Add("vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions {
+			if reflect {
+				w.Header().Add("vary", "Access-Control-Request-Method")
+				w.Header().Add("vary", "Access-Control-Request-Headers")
+			}
+			if origin != "" {
+				header := w.Header()
+				x.headers(header, origin)
+				header.Set("access-control-allow-methods", methods)
+				header.Set("access-control-allow-headers", headers)
+				if x.MaxAge > 0 {
+					header.Add("access-control-max-age", strconv.Itoa(int(x.MaxAge/time.Second)))
+				}
+			}
+
+			w.Write([]byte("OK"))
+		} else {
+			if origin != "" {
+				x.headers(w.Header(), origin)
+			}
+			h.ServeHTTP(w, r)
+		}
+	}), nil
+}
+
+// headers adds the headers common to both preflight and actual responses.
+func (x CORS) headers(header http.Header, origin string) {
+	header.Add("access-control-allow-origin", origin)
+	if x.Credentials {
+		header.Add("access-control-allow-credentials", "true")
+	}
+}
+
+// HandlerCORS wraps h to accept CORS requests from the specified origin.
+// The "*" wildcard origin accepts requests from any origin. Browsers do not allow it to be used for requests with credentials.
+func HandlerCORS(origin string, h http.Handler) http.Handler {
+	return HandlerCORSOrigins([]string{origin}, h)
+}
+
+// HandlerCORSOrigins wraps h to accept CORS requests from any of the specified origins.
+// See [CORS] for details.
+func HandlerCORSOrigins(origins []string, h http.Handler) http.Handler {
+	// cannot fail without credentials
+	o, _ := CORS{Origins: origins}.Wrap(h)
+	return o
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// digests maps supported Digest header algorithms to their hash constructors.
+var digests = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// digest returns the Digest header value of body, using the named algorithm.
+func digest(algorithm string, body []byte) (string, error) {
+	algorithm = strings.ToLower(algorithm)
+	f, ok := digests[algorithm]
+	if !ok {
+		return "", errors.New("http unsupported digest algorithm " + algorithm)
+	}
+	h := f()
+	h.Write(body)
+	return algorithm + "=" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// HandlerVerifyDigest returns a Handler that checks request bodies against their Digest header, rejecting mismatches with 400 Bad Request.
+// All listed digests that use a supported algorithm must match, and at least one must be present.
+// If required is false, requests without a Digest header are let through unchecked.
+//
+// As with [HMAC.Verify], the request body is held in memory, and should be limited to a reasonable size beforehand.
+func HandlerVerifyDigest(h http.Handler, required bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("digest")
+		if header == "" && !required {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := bodyBuffer(r)
+		if err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !digestVerify(header, body) {
+			http.Error(w, "request digest mismatch", http.StatusBadRequest)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// digestVerify reports whether the Digest header value matches body.
+func digestVerify(header string, body []byte) bool {
+	found := false
+	for _, part := range strings.Split(header, ",") {
+		algorithm, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		f, ok := digests[strings.ToLower(algorithm)]
+		if !ok {
+			continue
+		}
+
+		sum, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return false
+		}
+		h := f()
+		h.Write(body)
+		if !bytes.Equal(sum, h.Sum(nil)) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	stdio "io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/blitz-frost/io"
+	"github.com/klauspost/compress/zstd"
+)
+
+// decoders maps supported content encodings to their decompressor constructors.
+var decoders = map[string]func(stdio.Reader) (stdio.ReadCloser, error){
+	"br": func(r stdio.Reader) (stdio.ReadCloser, error) {
+		return stdio.NopCloser(brotli.NewReader(r)), nil
+	},
+	"gzip": func(r stdio.Reader) (stdio.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"zstd": func(r stdio.Reader) (stdio.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// encoders maps supported content encodings to their compressor constructors.
+var encoders = map[string]func(stdio.Writer) stdio.WriteCloser{
+	"br": func(w stdio.Writer) stdio.WriteCloser {
+		return brotli.NewWriter(w)
+	},
+	"gzip": func(w stdio.Writer) stdio.WriteCloser {
+		return gzip.NewWriter(w)
+	},
+	"zstd": func(w stdio.Writer) stdio.WriteCloser {
+		// only fails on invalid options
+		e, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return e
+	},
+}
+
+// encodingOrder lists the supported content encodings, most preferred first.
+var encodingOrder = []string{"zstd", "br", "gzip"}
+
+// acceptEncoding is the Accept-Encoding header value sent by Clients, weighing the supported encodings according to encodingOrder.
+var acceptEncoding = func() string {
+	parts := make([]string, len(encodingOrder))
+	for i, name := range encodingOrder {
+		parts[i] = name
+		if i > 0 {
+			parts[i] += ";q=0." + strconv.Itoa(10-i)
+		}
+	}
+	return strings.Join(parts, ", ")
+}()
+
+// encode compresses b according to the Client configuration.
+// Returns the resulting body, along with the applied encoding, which is empty if b was left unchanged.
+func (x Client) encode(b []byte) ([]byte, string, error) {
+	if x.Encoding == "" || x.Encoding == "identity" || len(b) == 0 || len(b) < x.EncodingMin {
+		return b, "", nil
+	}
+
+	f, ok := encoders[x.Encoding]
+	if !ok {
+		return nil, "", errors.New("http unsupported content encoding " + x.Encoding)
+	}
+
+	var buf bytes.Buffer
+	w := f(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), x.Encoding, nil
+}
+
+// decode returns the response body, decompressed according to its content encoding.
+// Unsupported encodings are passed through as is, leaving the header in place.
+func decode(resp *http.Response) io.Reader {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("content-encoding")))
+	f, ok := decoders[encoding]
+	if !ok {
+		return resp.Body
+	}
+
+	// mimic the standard transport behaviour
+	resp.Header.Del("content-encoding")
+	resp.Header.Del("content-length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return &decoded{
+		f:    f,
+		body: resp.Body,
+	}
+}
+
+// decoded decompresses a response body.
+// The decompressor is created on the first Read, so that empty bodies are not mistaken for corrupt ones.
+type decoded struct {
+	f    func(stdio.Reader) (stdio.ReadCloser, error)
+	r    stdio.ReadCloser // nil until the first Read
+	body stdio.ReadCloser
+	err  error // decompressor creation error
+}
+
+// Close closes both the decompressor and the underlying body.
+func (x *decoded) Close() error {
+	var err error
+	if x.r != nil {
+		err = x.r.Close()
+	}
+	return errors.Join(err, x.body.Close())
+}
+
+func (x *decoded) Read(b []byte) (int, error) {
+	if x.r == nil && x.err == nil {
+		x.r, x.err = x.f(x.body)
+	}
+	if x.err != nil {
+		return 0, x.err
+	}
+	return x.r.Read(b)
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/blitz-frost/io"
+)
+
+// An ETagStore remembers the entity tags and bodies of responses, by request URL.
+// It enables Clients to make conditional requests, reusing the stored body when the server answers with 304 Not Modified.
+//
+// Implementations must be safe for concurrent use.
+type ETagStore interface {
+	// Get returns the entity tag and body stored for url, if any.
+	Get(url string) (etag string, body []byte, ok bool)
+	// Set stores the entity tag and body of the latest response from url.
+	// body must not be modified afterwards.
+	Set(url, etag string, body []byte)
+}
+
+// ETagMemory is an in-memory [ETagStore].
+// Entries are never evicted, so it is best suited to a bounded set of URLs.
+type ETagMemory struct {
+	mu sync.Mutex
+	m  map[string]etagEntry
+}
+
+type etagEntry struct {
+	tag  string
+	body []byte
+}
+
+func ETagMemoryMake() *ETagMemory {
+	return &ETagMemory{
+		m: make(map[string]etagEntry),
+	}
+}
+
+func (x *ETagMemory) Get(url string) (string, []byte, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	e, ok := x.m[url]
+	return e.tag, e.body, ok
+}
+
+func (x *ETagMemory) Set(url, etag string, body []byte) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.m[url] = etagEntry{etag, body}
+}
+
+// etagRequest makes the exchange request conditional on the stored entity tag, if any.
+// Returns the URL under which the response should be stored and the previously stored entry, if found.
+// Range requests are left alone, as the stored body is a complete one.
+func (x *ClientWriter) etagRequest(p payload) (string, *etagEntry, error) {
+	if x.header.Get("range") != "" {
+		return "", nil, nil
+	}
+
+	url, err := addrMake(x.cli.addrs[0], p.path, p.query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tag, body, ok := x.cli.ETags.Get(url)
+	if !ok {
+		return url, nil, nil
+	}
+	p.header.Set("if-none-match", tag)
+	return url, &etagEntry{tag, body}, nil
+}
+
+// etagReader returns a ClientReader over the stored body, in place of a 304 Not Modified response.
+func (x Client) etagReader(resp *http.Response, e *etagEntry, cancel context.CancelFunc) *ClientReader {
+	resp.Body.Close()
+	body := io.BytesReader(e.body)
+	return &ClientReader{
+		r:      &body,
+		resp:   resp,
+		cancel: cancel,
+	}
+}
+
+// etagCapture wraps r so that its body is stored under url once fully read, if the response carries an entity tag.
+func (x Client) etagCapture(url string, r *ClientReader) {
+	tag := r.resp.Header.Get("etag")
+	if tag == "" || r.resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	r.r = &captureReader{
+		r: r.r,
+		done: func(body []byte) {
+			x.ETags.Set(url, tag, body)
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// An EventWriter streams Server-Sent Events over a response.
+// Each Write call sends its data as a single event, which is flushed to the client immediately.
+type EventWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// EventWriterMake prepares w for streaming Server-Sent Events, and sends the response headers.
+// w may be the Writer given out by a HandlerReader.
+//
+// Returns an error if w does not support flushing, in which case streaming events is not possible.
+func EventWriterMake(w http.ResponseWriter) (*EventWriter, error) {
+	header := w.Header()
+	header.Set("content-type", "text/event-stream")
+	header.Set("cache-control", "no-cache")
+	header.Del("content-length")
+
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, fmt.Errorf("http response does not support event streaming: %w", err)
+	}
+
+	return &EventWriter{
+		w:  w,
+		rc: rc,
+	}, nil
+}
+
+// Close is a NoOp. The event stream ends when the request handler returns.
+func (x *EventWriter) Close() error {
+	return nil
+}
+
+// Event sends a named event. An empty name is equivalent to a Write call.
+func (x *EventWriter) Event(name string, data []byte) error {
+	var buf bytes.Buffer
+	if name != "" {
+		buf.WriteString("event: " + name + "\n")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	if _, err := x.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return x.rc.Flush()
+}
+
+func (x *EventWriter) Write(b []byte) (int, error) {
+	if err := x.Event("", b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
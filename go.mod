@@ -2,6 +2,10 @@ module github.com/blitz-frost/http
 
 go 1.22
 
-require github.com/blitz-frost/io v0.2.8
+require (
+	github.com/andybalholm/brotli v1.2.5
+	github.com/blitz-frost/io v0.2.8
+	github.com/klauspost/compress v1.17.11
+)
 
 require github.com/blitz-frost/msg v0.1.1 // indirect
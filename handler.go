@@ -0,0 +1,332 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	stdio "io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blitz-frost/io"
+	"github.com/blitz-frost/io/msg"
+)
+
+var methodsDefault = []string{http.MethodPost}
+
+// Handler is a bridge between standard http request handling and the msg framework.
+//
+// The zero value is directly usable.
+type Handler struct {
+	// Methods lists the allowed request methods.
+	// Other methods are answered with 405 Method Not Allowed.
+	// If nil, only POST is allowed.
+	Methods []string
+
+	// MaxBodySize limits the size of request bodies.
+	// Requests that exceed it are answered with 413 Request Entity Too Large.
+	// Non-positive values disable the limit.
+	MaxBodySize int64
+
+	// ErrorStatus maps errors returned by the chained ExchangeReaderTaker to response status codes.
+	// If nil, all errors result in 400 Bad Request.
+	ErrorStatus func(error) int
+
+	// ErrorBody formats errors returned by the chained ExchangeReaderTaker into a response body, along with its content type.
+	// It is not used if the response has already been written to.
+	// If nil, error responses have no body.
+	ErrorBody func(err error, code int) (contentType string, body []byte)
+
+	// Panic is called with the value of any recovered panic, after a 500 Internal Server Error is sent.
+	// May be nil.
+	Panic func(any)
+
+	ert msg.ExchangeReaderTaker
+
+	mu     sync.Mutex
+	active sync.WaitGroup // in-flight requests
+	closed bool           // Shutdown was called
+}
+
+// The ExchangeReaders passed to ert are HandlerReaders.
+//
+// If [ert.ReaderTake] returns an error before anything was written to the response Writer, an error response is sent,
+// with the status given by [Handler.ErrorStatus], which defaults to 400 Bad Request.
+// Once the response has been written to, its status can no longer change, so errors are not reported to the client anymore.
+// Otherwise, a 200 OK is returned, along with any data written by the time [ert.ReaderTake] returns.
+func (x *Handler) ReaderChain(ert msg.ExchangeReaderTaker) error {
+	x.ert = ert
+	return nil
+}
+
+func (x *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	methods := x.Methods
+	if methods == nil {
+		methods = methodsDefault
+	}
+	if !slices.Contains(methods, r.Method) {
+		w.Header().Set("allow", strings.Join(methods, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	x.mu.Lock()
+	if x.closed {
+		x.mu.Unlock()
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	x.active.Add(1)
+	x.mu.Unlock()
+	defer x.active.Done()
+
+	resp := &writerResp{ResponseWriter: w}
+
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		if v == http.ErrAbortHandler {
+			// standard way of aborting a response; let the server deal with it
+			panic(v)
+		}
+
+		if !resp.written {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if x.Panic != nil {
+			x.Panic(v)
+		}
+	}()
+
+	var (
+		src   stdio.Reader = r.Body
+		limit *bodyLimit
+	)
+	if x.MaxBodySize > 0 {
+		limit = &bodyLimit{r: http.MaxBytesReader(w, r.Body, x.MaxBodySize)}
+		src = limit
+	}
+
+	// unblock pending reads once the request is canceled, such as when the client goes away
+	ctx := r.Context()
+	src = bodyContext{ctx: ctx, r: src}
+	stop := context.AfterFunc(ctx, func() {
+		http.NewResponseController(w).SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	err := x.ert.ReaderTake(HandlerReader{
+		r:   io.ReaderOf(src),
+		w:   resp,
+		req: r,
+	})
+
+	if err != nil && !resp.written {
+		x.errorWrite(w, err, x.errorStatus(err, limit))
+	}
+
+	// leftover body data would prevent the connection from being reused; bounded, so that clients cannot keep the Handler busy
+	stdio.CopyN(stdio.Discard, src, drainMax)
+}
+
+// Shutdown makes the Handler answer new requests with 503 Service Unavailable, and waits for in-flight ones to finish.
+// If ctx is done first, Shutdown returns its error, leaving the remaining requests running.
+//
+// Calling it before [http.Server.Shutdown] drains the Handler's exchanges first, which also works when the server hosts other handlers.
+func (x *Handler) Shutdown(ctx context.Context) error {
+	x.mu.Lock()
+	x.closed = true
+	x.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		x.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errorWrite writes the error response for err.
+func (x *Handler) errorWrite(w http.ResponseWriter, err error, code int) {
+	if x.ErrorBody == nil {
+		w.WriteHeader(code)
+		return
+	}
+
+	contentType, b := x.ErrorBody(err, code)
+	header := w.Header()
+	header.Set("content-type", contentType)
+	header.Set("x-content-type-options", "nosniff")
+	header.Del("content-length")
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// ErrorText is a [Handler.ErrorBody] function that uses the error message as a plain text body.
+func ErrorText(err error, code int) (string, []byte) {
+	return "text/plain; charset=utf-8", []byte(err.Error())
+}
+
+// errorStatus returns the response status code corresponding to err.
+// limit may be nil.
+func (x *Handler) errorStatus(err error, limit *bodyLimit) int {
+	if limit != nil && limit.exceeded {
+		return http.StatusRequestEntityTooLarge
+	}
+	if x.ErrorStatus != nil {
+		return x.ErrorStatus(err)
+	}
+	return http.StatusBadRequest
+}
+
+// bodyContext wraps a request body to fail reads once its context is done.
+type bodyContext struct {
+	ctx context.Context
+	r   stdio.Reader
+}
+
+func (x bodyContext) Read(b []byte) (int, error) {
+	if x.ctx.Err() != nil {
+		return 0, fmt.Errorf("http request aborted: %w", context.Cause(x.ctx))
+	}
+	n, err := x.r.Read(b)
+	if err != nil && err != stdio.EOF && x.ctx.Err() != nil {
+		err = fmt.Errorf("http request aborted: %w", context.Cause(x.ctx))
+	}
+	return n, err
+}
+
+// bodyLimit records whether a request body exceeded its [http.MaxBytesReader] limit.
+type bodyLimit struct {
+	r        stdio.Reader
+	exceeded bool
+}
+
+func (x *bodyLimit) Read(b []byte) (int, error) {
+	n, err := x.r.Read(b)
+	var e *http.MaxBytesError
+	if errors.As(err, &e) {
+		x.exceeded = true
+	}
+	return n, err
+}
+
+// A HandlerReader is the [msg.ExchangeReader] implementation passed on by a Handler.
+type HandlerReader struct {
+	r   msg.Reader
+	w   msg.Writer
+	req *http.Request
+}
+
+// Close is a NoOp. The request body is drained, within limits, and closed automatically when ServeHTTP returns.
+func (x HandlerReader) Close() error {
+	return nil
+}
+
+// Deadline returns the time by which the request should be handled, if set by an upstream timeout, such as HandlerTimeout.
+// Handlers should honor it, as any work done past it is wasted; the request context is canceled at that time.
+func (x HandlerReader) Deadline() (time.Time, bool) {
+	return x.req.Context().Deadline()
+}
+
+// Context returns the request context, which is canceled if the client goes away.
+func (x HandlerReader) Context() context.Context {
+	return x.req.Context()
+}
+
+// Read reads from the request body.
+// It fails promptly once the request context is done, rather than waiting on a client that went away.
+func (x HandlerReader) Read(b []byte) (int, error) {
+	return x.r.Read(b)
+}
+
+// RemoteAddr returns the network address of the client, as reported by [http.Request.RemoteAddr].
+func (x HandlerReader) RemoteAddr() string {
+	return x.req.RemoteAddr
+}
+
+// TLS returns the state of the TLS connection the request was received on, or nil if it was not encrypted.
+func (x HandlerReader) TLS() *tls.ConnectionState {
+	return x.req.TLS
+}
+
+// Writer returns the response Writer.
+// Besides msg.Writer, it implements [http.ResponseWriter] and [http.Flusher], as well as:
+//
+//	SetTrailer(key, value string)
+//
+// which sets a trailer, sent after the response body.
+//
+// Calling WriteHeader before writing sets an explicit response status, such as 201 Created, which the Handler leaves untouched.
+func (x HandlerReader) Writer() (msg.Writer, error) {
+	return x.w, nil
+}
+
+// writerResp is the response Writer given out by HandlerReader.
+// Besides being an [http.ResponseWriter], it also implements [http.Flusher] for streaming responses.
+// It records whether the response headers have been sent, after which the status code can no longer be changed.
+type writerResp struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (x *writerResp) Close() error {
+	return nil
+}
+
+// Flush implements [http.Flusher], sending any buffered data to the client.
+// It is a NoOp if the underlying ResponseWriter does not support flushing.
+func (x *writerResp) Flush() {
+	x.FlushError()
+}
+
+// FlushError is like Flush, but returns an error if flushing is not supported.
+func (x *writerResp) FlushError() error {
+	// flushing sends the headers
+	x.written = true
+	return http.NewResponseController(x.ResponseWriter).Flush()
+}
+
+// SetTrailer sets a response trailer value.
+// Trailers set before the body is written are announced in the response headers, and their values may still be changed afterwards.
+// Trailers first set afterwards are only sent if the response headers were already flushed, which makes the response chunked.
+func (x *writerResp) SetTrailer(key, value string) {
+	header := x.Header()
+	key = http.CanonicalHeaderKey(key)
+	if !x.written && !slices.Contains(header.Values("trailer"), key) {
+		header.Add("trailer", key)
+	}
+	header.Set(http.TrailerPrefix+key, value)
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying ResponseWriter.
+func (x *writerResp) Unwrap() http.ResponseWriter {
+	return x.ResponseWriter
+}
+
+func (x *writerResp) Write(b []byte) (int, error) {
+	x.written = true
+	return x.ResponseWriter.Write(b)
+}
+
+// WriteHeader sends the response headers with the given status code.
+// Informational codes may be sent any number of times beforehand.
+func (x *writerResp) WriteHeader(code int) {
+	// informational responses may precede the final one
+	if code >= 200 {
+		x.written = true
+	}
+	x.ResponseWriter.WriteHeader(code)
+}
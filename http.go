@@ -2,17 +2,128 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	stdio "io"
 	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/blitz-frost/io"
 	"github.com/blitz-frost/io/msg"
 )
 
-// A Client that exchanges data with a set endpoint through HTTP POST.
+// A Client that exchanges data with a set endpoint through HTTP requests, POST by default.
+//
+// The exported fields may be modified after construction, in order to configure the Client.
 type Client struct {
-	addr string
-	cli  *http.Client
+	Method      string      // HTTP method used for requests
+	ContentType string      // request body content type
+	Accept      string      // accepted response content types, unless overridden by a header; if empty, "*/*" is sent
+	UserAgent   string      // sent with every request, unless overridden by a header; if empty, Go's default is used
+	Header      http.Header // sent with every request; may be nil
+
+	// StatusAccept reports whether a response status code denotes success.
+	// If nil, all 2xx codes are accepted, as well as 3xx codes when Redirect is set.
+	StatusAccept func(int) bool
+
+	// Redirect replaces the redirect policy of the underlying http.Client, as per [http.Client.CheckRedirect].
+	// Redirects that are not followed result in the 3xx response being returned to the caller.
+	// If nil, the http.Client policy applies, which follows up to 10 redirects by default.
+	Redirect func(req *http.Request, via []*http.Request) error
+
+	// Jar replaces the cookie jar of the underlying http.Client, storing cookies set by responses and sending them with later requests.
+	// If nil, the http.Client jar applies, which is none by default.
+	Jar http.CookieJar
+
+	Retry Retry // disabled by default
+
+	// Encoding is the content encoding applied to buffered request bodies of at least EncodingMin bytes.
+	// Empty or "identity" leaves bodies unencoded. Supported encodings are "zstd", "br" and "gzip".
+	//
+	// Regardless of Encoding, Clients advertise all supported encodings through the Accept-Encoding header, unless set explicitly,
+	// in the above order of preference, and transparently decompress responses that use them.
+	Encoding    string
+	EncodingMin int
+
+	// ExpectContinue makes requests with a body send an "Expect: 100-continue" header, and hold the body back until the server agrees to receive it.
+	// If the server responds with a final status instead, such as 413 Request Entity Too Large, the body is never sent, and the response is handled as usual.
+	// This relies on the transport ExpectContinueTimeout, which is 1 second for the default transport. If zero, the body is sent right away.
+	ExpectContinue bool
+
+	// BufferSize is the initial capacity of buffered exchange Writers, in bytes.
+	// Setting it to the typical request size avoids repeated reallocations while writing.
+	BufferSize int
+
+	// Auth is called on each outgoing request, in order to attach credentials.
+	// May be nil.
+	Auth func(*http.Request) error
+
+	// OnResponse is called after each request attempt, whether it succeeded or not.
+	// Panics inside it are recovered and ignored. May be nil.
+	OnResponse func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)
+
+	// WriteQuery makes buffered exchanges use the written data as URL encoded query parameters, instead of as the request body.
+	// The parameters are added to any query already present in the Client address.
+	WriteQuery bool
+
+	// Idempotency makes buffered exchanges send an Idempotency-Key header, with a random UUID that stays the same across retries.
+	// Exchanges with an explicit [ClientWriter.SetIdempotencyKey] always send the header.
+	Idempotency bool
+
+	// Digest makes buffered exchanges send a checksum of their request body, as sent over the network, in a Digest header.
+	// Supported algorithms are "sha-256" and "sha-512". If empty, no Digest header is sent.
+	Digest string
+
+	// Sign makes buffered exchanges sign their request body, as sent over the network.
+	// With WriteQuery, the signed body is empty.
+	// Retries reuse the same signature. May be nil.
+	Sign *HMAC
+
+	// MaxResponseSize limits the size of response bodies, after decompression.
+	// Reading past it fails with ErrResponseSize. Non-positive values disable the limit.
+	MaxResponseSize int64
+
+	// Failover reports whether a buffered exchange request should be retried on the next Client endpoint, if any.
+	// Exactly one of resp and err is non-nil.
+	// If nil, only transport errors move on to the next endpoint.
+	// Errors building the request, such as Auth failures, are returned without trying other endpoints.
+	Failover func(resp *http.Response, err error) bool
+
+	// UploadProgress is called with the number of request body bytes sent so far, along with the total, which is -1 for streamed bodies.
+	// It runs on a separate goroutine, so that it does not hold up the transfer. Updates that arrive while it is busy are merged.
+	// Retried requests report their progress from 0 again.
+	// May be nil.
+	UploadProgress func(sent, total int64)
+
+	// DownloadProgress is like UploadProgress, but reports the response body bytes received, after decompression.
+	// total is -1 if the response length is not known in advance, as is the case for compressed responses.
+	DownloadProgress func(received, total int64)
+
+	Metrics *Metrics  // collects request statistics; may be nil
+	Breaker *Breaker  // guards against a failing upstream; may be nil
+	Cache   *Cache    // reuses responses to buffered GET exchanges; may be nil
+	ETags   ETagStore // makes buffered GET exchanges conditional on previously seen entity tags; may be nil
+
+	// Concurrency limits the number of requests awaiting a response at any given time.
+	// Requests over the limit block until they are allowed through, or their context is done.
+	// May be nil.
+	Concurrency Semaphore
+
+	// Trace is called before each request attempt, in order to integrate with tracing systems.
+	// It returns the request to actually send, which may be req itself, or a derived request carrying a span context and propagation headers.
+	// The returned end function, if not nil, is called once the attempt completes, regardless of outcome.
+	// May be nil.
+	Trace func(req *http.Request) (*http.Request, func(resp *http.Response, err error))
+
+	addrs   []string  // endpoints, in order of preference
+	balance *balancer // may be nil
+	cli     *http.Client
 }
 
 // ClientMake returns a unsable Client.
@@ -22,111 +133,1007 @@ func ClientMake(addr string, cli *http.Client) Client {
 		cli = http.DefaultClient
 	}
 	return Client{
-		addr: addr,
-		cli:  cli,
+		Method:      http.MethodPost,
+		ContentType: "application/octet-stream",
+		addrs:       []string{addr},
+		cli:         cli,
+	}
+}
+
+// ClientCookies returns a Client with a fresh in-memory cookie jar, so that session cookies persist across its exchanges.
+// cli may be nil, in which case the default http client is used.
+func ClientCookies(addr string, cli *http.Client) Client {
+	x := ClientMake(addr, cli)
+	// never fails without options
+	x.Jar, _ = cookiejar.New(nil)
+	return x
+}
+
+// RedirectNone is a [Client.Redirect] policy that never follows redirects.
+func RedirectNone(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+func (x Client) statusAccept(code int) bool {
+	if x.StatusAccept == nil {
+		if x.Redirect != nil && code >= 300 && code < 400 {
+			return true
+		}
+		return code >= 200 && code < 300
+	}
+	return x.StatusAccept(code)
+}
+
+// do sends req, making context cancellation explicit in the returned error.
+// If attempts is not nil, it is incremented if the request is actually handed to the http.Client, rather than held back by Concurrency or Breaker.
+func (x Client) do(req *http.Request, attempts *int) (*http.Response, error) {
+	// acquired first, so that an allowed breaker request is always followed by its outcome
+	if x.Concurrency != nil {
+		if err := x.Concurrency.acquire(req.Context()); err != nil {
+			// the body must be closed, as the transport would have
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, fmt.Errorf("http request aborted: %w", err)
+		}
+		defer x.Concurrency.release()
+	}
+
+	if x.Breaker != nil {
+		if !x.Breaker.allow() {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, ErrCircuitOpen
+		}
+	}
+
+	var end func(*http.Response, error)
+	if x.Trace != nil {
+		req, end = x.Trace(req)
+	}
+	if x.Metrics != nil {
+		x.Metrics.inFlight.Add(1)
+	}
+
+	cli := x.cli
+	if x.Redirect != nil || x.Jar != nil {
+		c := *cli
+		if x.Redirect != nil {
+			c.CheckRedirect = x.Redirect
+		}
+		if x.Jar != nil {
+			c.Jar = x.Jar
+		}
+		cli = &c
+	}
+
+	if attempts != nil {
+		*attempts++
+	}
+	start := time.Now()
+	resp, err := cli.Do(req)
+	elapsed := time.Since(start)
+
+	if end != nil {
+		end(resp, err)
+	}
+	if x.Breaker != nil {
+		if err != nil && req.Context().Err() != nil {
+			x.Breaker.abandon()
+		} else {
+			x.Breaker.record(err != nil || resp.StatusCode >= 500)
+		}
+	}
+	if x.Metrics != nil {
+		code := 0
+		if resp != nil {
+			code = resp.StatusCode
+		}
+		x.Metrics.record(code, elapsed)
+	}
+	if x.OnResponse != nil {
+		x.onResponse(req, resp, err, elapsed)
+	}
+	if err != nil {
+		if ctx := req.Context(); ctx.Err() != nil {
+			return nil, fmt.Errorf("http request aborted: %w", context.Cause(ctx))
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (x Client) onResponse(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	defer func() {
+		recover()
+	}()
+	x.OnResponse(req, resp, err, elapsed)
+}
+
+// reader validates the response status and wraps the response body.
+// cancel is called when the response is discarded or its reader closed; it may be nil.
+func (x Client) reader(ctx context.Context, resp *http.Response, cancel context.CancelFunc) (msg.Reader, error) {
+	if !x.statusAccept(resp.StatusCode) {
+		if cancel != nil {
+			defer cancel()
+		}
+		return nil, statusError(resp)
+	}
+
+	drain := drainable(resp)
+	r := decode(resp)
+	if x.DownloadProgress != nil {
+		r = x.downloadProgress(r, resp.ContentLength)
+	}
+	if x.MaxResponseSize > 0 {
+		r = &limitReader{
+			r: r,
+			n: x.MaxResponseSize,
+		}
+	}
+
+	return &ClientReader{
+		r: io.ReaderOf(body{
+			ctx: ctx,
+			r:   r,
+		}),
+		resp:   resp,
+		cancel: cancel,
+		drain:  drain,
+	}, nil
+}
+
+// statusError returns the error describing a rejected response, consuming and closing its body.
+func statusError(resp *http.Response) *StatusError {
+	drain := drainable(resp)
+	r := decode(resp)
+	b := make([]byte, statusBodyMax)
+	n, _ := io.ReaderOf(r).Read(b)
+	if drain {
+		stdio.Copy(stdio.Discard, resp.Body)
+	}
+	r.Close()
+	after, _ := retryAfter(resp.Header)
+	return &StatusError{
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		ContentType: resp.Header.Get("content-type"),
+		Body:        b[:n],
+		RetryAfter:  after,
 	}
 }
 
+// drainMax is the largest remaining body that is read to completion when discarded, so that the connection may be reused.
+const drainMax = 64 << 10
+
+// drainable reports whether the body of resp is small enough to drain, along with its length being known, so that draining cannot stall.
+// Must be called before decoding the body.
+func drainable(resp *http.Response) bool {
+	return resp.ContentLength >= 0 && resp.ContentLength <= drainMax
+}
+
+// discard closes the body of an unused response, draining it first if possible.
+func discard(resp *http.Response) {
+	if drainable(resp) {
+		stdio.Copy(stdio.Discard, resp.Body)
+	}
+	resp.Body.Close()
+}
+
+// request returns a request to addr, carrying the Client headers, overridden by each of headers in turn.
+func (x Client) request(ctx context.Context, addr string, body stdio.Reader, headers ...http.Header) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, x.Method, addr, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Header.Set("content-type", x.ContentType)
+		if x.ExpectContinue {
+			req.Header.Set("expect", "100-continue")
+		}
+	}
+	accept := x.Accept
+	if accept == "" {
+		accept = "*/*"
+	}
+	req.Header.Set("accept", accept)
+	if x.UserAgent != "" {
+		req.Header.Set("user-agent", x.UserAgent)
+	}
+	headerCopy(req.Header, x.Header)
+	for _, header := range headers {
+		headerCopy(req.Header, header)
+	}
+	// like the standard transport, leave partial responses uncompressed, so that Content-Range refers to the actual content
+	if req.Header.Get("accept-encoding") == "" && req.Header.Get("range") == "" {
+		req.Header.Set("accept-encoding", acceptEncoding)
+	}
+
+	if x.Auth != nil {
+		if err := x.Auth(req); err != nil {
+			return nil, err
+		}
+	}
+
+	// only once the request is certain to be sent, as the progress notifier runs until the body is closed
+	if x.UploadProgress != nil && req.Body != nil && req.Body != http.NoBody {
+		req.Body = x.uploadProgress(req.Body, req.ContentLength)
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (stdio.ReadCloser, error) {
+				r, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return x.uploadProgress(r, req.ContentLength), nil
+			}
+		}
+	}
+	return req, nil
+}
+
+// ClientFailover returns a Client that sends requests to addrs, in order of preference.
+// Buffered exchanges move on to the next address when a request fails, as reported by [Client.Failover].
+// If all addresses fail, the returned error joins the individual failures.
+// Streamed exchanges only use the first address.
+//
+// cli may be nil, in which case the default http client is used.
+func ClientFailover(addrs []string, cli *http.Client) Client {
+	x := ClientMake("", cli)
+	x.addrs = append([]string(nil), addrs...)
+	return x
+}
+
+func (x Client) failover(resp *http.Response, err error) bool {
+	if x.Failover != nil {
+		return x.Failover(resp, err)
+	}
+	return err != nil
+}
+
+// ClientBalanced returns a Client that distributes requests across addrs in round-robin fashion.
+// Failing addresses, as reported by [Client.Failover], are skipped for the cooldown duration, unless all of them are failing.
+// Otherwise, it behaves as a Client returned by ClientFailover.
+//
+// The balancing state is shared by all copies of the returned Client, and is safe for concurrent use.
+func ClientBalanced(addrs []string, cli *http.Client, cooldown time.Duration) Client {
+	x := ClientFailover(addrs, cli)
+	x.balance = balancerMake(len(addrs), cooldown)
+	return x
+}
+
+// order returns the endpoint indexes in the order they should be tried.
+func (x Client) order() []int {
+	if x.balance != nil {
+		return x.balance.order()
+	}
+	o := make([]int, len(x.addrs))
+	for i := range o {
+		o[i] = i
+	}
+	return o
+}
+
+// ClientGet returns a Client that sends GET requests, with the written data as query parameters.
+// See [ClientMake] and [Client.WriteQuery].
+func ClientGet(addr string, cli *http.Client) Client {
+	x := ClientMake(addr, cli)
+	x.Method = http.MethodGet
+	x.WriteQuery = true
+	return x
+}
+
+// Stats returns a snapshot of the Client Metrics.
+// Returns the zero value if the Client does not collect metrics.
+func (x Client) Stats() Stats {
+	if x.Metrics == nil {
+		return Stats{}
+	}
+	return x.Metrics.Stats()
+}
+
+// Writer is equivalent to WriterContext with a background context.
 func (x Client) Writer() (msg.ExchangeWriter, error) {
-	return &writer{
+	return x.WriterContext(context.Background())
+}
+
+// WriterContext returns an exchange Writer whose request is bound to ctx.
+// Canceling ctx aborts the exchange, whether the request is still being sent or the response is being read.
+//
+// The returned value is a *ClientWriter.
+// Returns an interface in order to satisfy the [msg.ExchangeWriterGiver] interface.
+func (x Client) WriterContext(ctx context.Context) (msg.ExchangeWriter, error) {
+	return &ClientWriter{
+		buf: x.bufGet(),
 		cli: x,
+		ctx: ctx,
 	}, nil
 }
 
-// Handler is a bridge between standard http request handling and the msg framework.
+// bufPool holds request buffers released by closed ClientWriters.
+var bufPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// bufPoolMax is the largest buffer capacity that is returned to the pool, so that occasional large requests don't pin their memory.
+const bufPoolMax = 64 << 10
+
+// bufGet returns a pooled buffer, with at least the configured capacity.
+func (x Client) bufGet() *bufShared {
+	buf := &bufShared{
+		Buffer: bufPool.Get().(*bytes.Buffer),
+	}
+	buf.refs.Store(1)
+	if x.BufferSize > 0 {
+		buf.Grow(x.BufferSize)
+	}
+	return buf
+}
+
+// bufShared is a request buffer, referenced by its ClientWriter and by each open request body that reads from it.
+// It returns to the pool once the last reference is released, so that the ClientWriter never waits for the transport to close request bodies.
+type bufShared struct {
+	*bytes.Buffer
+	refs atomic.Int32
+}
+
+// busy reports whether request bodies still reference the buffer.
+// Only meaningful while the ClientWriter holds its own reference.
+func (x *bufShared) busy() bool {
+	return x.refs.Load() > 1
+}
+
+func (x *bufShared) acquire() {
+	x.refs.Add(1)
+}
+
+func (x *bufShared) release() {
+	if x.refs.Add(-1) != 0 {
+		return
+	}
+	if x.Cap() <= bufPoolMax {
+		x.Reset()
+		bufPool.Put(x.Buffer)
+	}
+	x.Buffer = nil
+}
+
+// errClosed is returned when using a closed ClientWriter.
+var errClosed = errors.New("http writer closed")
+
+// A ClientWriter is the [msg.ExchangeWriter] implementation used by Client.
+// It buffers written data until the Reader method is called.
 //
-// The zero value is directly usable.
-type Handler struct {
-	ert msg.ExchangeReaderTaker
+// A ClientWriter may be reused for sequential exchanges through its Reset method, but must not be used concurrently.
+type ClientWriter struct {
+	buf     *bufShared // nil after Close
+	cli     Client
+	ctx     context.Context
+	header  http.Header
+	query   url.Values
+	timeout time.Duration
+	key     string // idempotency key
+	path    string
+
+	// failures of the preceding endpoints, when the last one tried (at failAddr) responded; joined with its status error, if rejected
+	failures []error
+	failAddr string
+
+	// statistics of the last Reader call
+	elapsed  time.Duration
+	attempts int
+
+	busy    sync.Mutex // held by Reader and Close
+	mu      sync.Mutex
+	abort   context.CancelCauseFunc // cancels the Reader request while in flight; may be nil
+	closing bool                    // a Close call is waiting for Reader to return
 }
 
-// In order to return a http BadRequest, [ert] should return an error when reading, without using the associated response Writer.
-// In any other case, a http OK will be returned, as well as any data written by the time [ert.ReaderTake] returns.
-func (x *Handler) ReaderChain(ert msg.ExchangeReaderTaker) error {
-	x.ert = ert
+// Close releases the internal buffer for reuse by other ClientWriters, once the transport has closed any request bodies still reading from it.
+// The ClientWriter becomes unusable.
+//
+// If a Reader call is underway, Close aborts its request, and waits for it to return.
+// A ClientReader that was already returned remains usable, and must still be closed on its own.
+func (x *ClientWriter) Close() error {
+	x.mu.Lock()
+	x.closing = true
+	if x.abort != nil {
+		x.abort(errClosed)
+	}
+	x.mu.Unlock()
+
+	x.busy.Lock()
+	defer x.busy.Unlock()
+
+	x.mu.Lock()
+	x.closing = false
+	x.mu.Unlock()
+
+	if x.buf == nil {
+		return nil
+	}
+
+	x.buf.release()
+	x.buf = nil
 	return nil
 }
 
-func (x *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	err := x.ert.ReaderTake(reader{
-		r: io.ReaderOf(r.Body),
-		w: writerResp{w},
-	})
+// abortSet installs the cancel function of the Reader request, or clears it if nil.
+// Returns false if a Close call is underway, in which case nothing is installed.
+func (x *ClientWriter) abortSet(abort context.CancelCauseFunc) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
 
+	if x.closing && abort != nil {
+		return false
+	}
+	x.abort = abort
+	return true
+}
+
+// Header returns the header map that will be sent with this exchange's request, on top of the Client headers.
+// Modifications must be made before calling Reader.
+func (x *ClientWriter) Header() http.Header {
+	if x.header == nil {
+		x.header = make(http.Header)
+	}
+	return x.header
+}
+
+// AddQuery adds query parameters to this exchange's request URL, on top of those already present in the Client address.
+// Must be called before Reader.
+func (x *ClientWriter) AddQuery(v url.Values) {
+	if x.query == nil {
+		x.query = make(url.Values, len(v))
+	}
+	for k, vs := range v {
+		x.query[k] = append(x.query[k], vs...)
+	}
+}
+
+// Reader sends the http request and returns a response reader.
+// If nothing was written, the request is sent without a body.
+//
+// The returned value is a *ClientReader.
+func (x *ClientWriter) Reader() (msg.Reader, error) {
+	x.busy.Lock()
+	defer x.busy.Unlock()
+
+	if x.buf == nil {
+		return nil, errClosed
+	}
+
+	start := time.Now()
+	x.elapsed, x.attempts = 0, 0
+
+	// abort allows Close to cancel the request at any point before the response arrives
+	ctx, abort := context.WithCancelCause(x.ctx)
+	cancel := func() {
+		abort(nil)
+	}
+	if x.timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, x.timeout, ErrTimeout)
+		cancel = func() {
+			stop()
+			abort(nil)
+		}
+	}
+	if !x.abortSet(abort) {
+		cancel()
+		return nil, errClosed
+	}
+	fail := func(err error) (msg.Reader, error) {
+		x.abortSet(nil)
+		cancel()
+		return nil, err
+	}
+
+	p, err := x.prepare()
+	if err != nil {
+		return fail(err)
+	}
+
+	var key string
+	if x.cli.Cache != nil && x.cli.Method == http.MethodGet && len(x.cli.addrs) > 0 {
+		if key, err = x.cacheKey(p); err != nil {
+			return fail(err)
+		}
+		if r, ok := x.cli.Cache.reader(key); ok {
+			x.abortSet(nil)
+			cancel()
+			return x.stats(r, start), nil
+		}
+	}
+
+	var (
+		url    string
+		stored *etagEntry
+	)
+	if x.cli.ETags != nil && x.cli.Method == http.MethodGet && len(x.cli.addrs) > 0 {
+		if url, stored, err = x.etagRequest(p); err != nil {
+			return fail(err)
+		}
+	}
+
+	resp, err := x.do(ctx, p)
+	x.elapsed = time.Since(start)
+	x.abortSet(nil)
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if stored != nil && resp.StatusCode == http.StatusNotModified {
+		return x.stats(x.cli.etagReader(resp, stored, cancel), start), nil
+	}
+
+	mr, err := x.cli.reader(ctx, resp, cancel)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		if len(x.failures) > 0 {
+			err = errors.Join(append(x.failures, fmt.Errorf("endpoint %s: %w", x.failAddr, err))...)
+		}
+		return nil, err
 	}
+	r := mr.(*ClientReader)
+	if key != "" {
+		x.cli.Cache.capture(key, r)
+	}
+	if url != "" {
+		x.cli.etagCapture(url, r)
+	}
+	return x.stats(r, start), nil
 }
 
-// reader is the [msg.ExchangeReader] implementation
-type reader struct {
-	r msg.Reader
-	w msg.Writer
+// stats records the exchange statistics in r, and returns it.
+func (x *ClientWriter) stats(r *ClientReader, start time.Time) *ClientReader {
+	if x.elapsed == 0 {
+		x.elapsed = time.Since(start)
+	}
+	r.elapsed = x.elapsed
+	r.attempts = x.attempts
+	return r
 }
 
-// the request body will be closed automatically on ServeHTTP return.
-func (x reader) Close() error {
-	return nil
+// Attempts returns the number of requests sent by the last Reader call, including retries and failovers.
+// It is zero if the response came from the Client Cache, or the Reader call failed before sending anything.
+func (x *ClientWriter) Attempts() int {
+	return x.attempts
 }
 
-func (x reader) Read(b []byte) (int, error) {
-	return x.r.Read(b)
+// Elapsed returns the duration of the last Reader call, which ends when the response headers arrive, or the exchange fails.
+func (x *ClientWriter) Elapsed() time.Duration {
+	return x.elapsed
 }
 
-func (x reader) Writer() (msg.Writer, error) {
-	return x.w, nil
+// CopyTo performs the exchange, copying the response body to w instead of returning a Reader.
+// Returns the number of bytes copied.
+// As with Reader, the copy is aborted if the exchange context is done.
+func (x *ClientWriter) CopyTo(w stdio.Writer) (int64, error) {
+	r, err := x.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	return stdio.Copy(w, r)
 }
 
-// writer is the [msg.ExchangeWriter] implementation
-type writer struct {
-	buf bytes.Buffer
-	cli Client
+// Reset discards all written data and per exchange settings, making the ClientWriter ready for a new exchange under the same context.
+// Any previously obtained ClientReader should be closed beforehand.
+//
+// Reset may also be used to revive a closed ClientWriter.
+func (x *ClientWriter) Reset() {
+	if x.buf != nil && x.buf.busy() {
+		// still being read by the transport; leave it to the last request body
+		x.buf.release()
+		x.buf = nil
+	}
+	if x.buf == nil {
+		x.buf = x.cli.bufGet()
+	}
+	x.buf.Reset()
+	x.header = nil
+	x.query = nil
+	x.timeout = 0
+	x.key = ""
+	x.path = ""
+	x.elapsed, x.attempts = 0, 0
 }
 
-func (x *writer) Close() error {
-	return nil
+// SetIdempotencyKey sets the Idempotency-Key header value sent with this exchange's request, and any of its retries.
+// An empty key reverts to the Client configuration.
+func (x *ClientWriter) SetIdempotencyKey(key string) {
+	x.key = key
+}
+
+// SetPath makes the exchange address the given path, relative to that of the Client address, which is otherwise preserved.
+// This allows a single Client to reach any resource of a host.
+// An empty path reverts to the Client address.
+func (x *ClientWriter) SetPath(path string) {
+	x.path = path
+}
+
+// SetTimeout limits the duration of the exchange, starting from the Reader call and ending when the response is fully read.
+// When exceeded, the exchange is aborted with an error matching ErrTimeout.
+// Non-positive values disable the timeout, which is the default.
+func (x *ClientWriter) SetTimeout(d time.Duration) {
+	x.timeout = d
+}
+
+// do sends the request, retrying as configured by the Client.
+func (x *ClientWriter) do(ctx context.Context, p payload) (*http.Response, error) {
+	var (
+		retry = x.cli.Retry
+		delay = retry.Backoff
+	)
+	for attempt := 0; ; attempt++ {
+		resp, err := x.send(ctx, p)
+		if e, ok := err.(errRequest); ok {
+			return nil, e.err
+		}
+		if attempt >= retry.Max || ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) || !retry.should(resp, err) {
+			return resp, err
+		}
+
+		wait := retry.after(resp, retry.wait(delay))
+
+		// don't bother waiting if the retry would overshoot the deadline anyway
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+		if retry.Budget != nil && !retry.Budget.take() {
+			return resp, err
+		}
+
+		if resp != nil {
+			discard(resp)
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("http request aborted: %w", err)
+		}
+		delay = retry.next(delay)
+	}
+}
+
+// payload holds the final request data of a buffered exchange.
+type payload struct {
+	path   string // to join with the endpoint address path
+	query  string // encoded query parameters to add to the endpoint address
+	body   []byte
+	header http.Header // describes the body
+}
+
+// prepare returns the final request data, based on what was written.
+func (x *ClientWriter) prepare() (payload, error) {
+	p := payload{
+		path:   x.path,
+		query:  x.query.Encode(),
+		header: make(http.Header),
+	}
+
+	// generated once per exchange, so that the server can recognize retries
+	if key := x.key; key != "" || x.cli.Idempotency {
+		if key == "" {
+			key = uuid()
+		}
+		p.header.Set("idempotency-key", key)
+	}
+
+	if x.cli.WriteQuery {
+		if q := x.buf.String(); q != "" {
+			if p.query != "" {
+				p.query += "&"
+			}
+			p.query += q
+		}
+	} else {
+		b, encoding, err := x.cli.encode(x.buf.Bytes())
+		if err != nil {
+			return payload{}, err
+		}
+		if encoding != "" {
+			p.header.Set("content-encoding", encoding)
+		}
+		p.body = b
+	}
+
+	if x.cli.Digest != "" {
+		v, err := digest(x.cli.Digest, p.body)
+		if err != nil {
+			return payload{}, err
+		}
+		p.header.Set("digest", v)
+	}
+	if x.cli.Sign != nil {
+		x.cli.Sign.signHeader(p.header, p.body)
+	}
+
+	return p, nil
+}
+
+// send performs a single request attempt, failing over to the next Client endpoint as configured.
+func (x *ClientWriter) send(ctx context.Context, p payload) (*http.Response, error) {
+	var (
+		errs  []error
+		order = x.cli.order()
+	)
+	x.failures = nil
+	for j, i := range order {
+		addr := x.cli.addrs[i]
+		resp, err := x.sendTo(ctx, addr, p)
+		if _, ok := err.(errRequest); ok {
+			return nil, err
+		}
+
+		failed := ctx.Err() == nil && !errors.Is(err, ErrCircuitOpen) && x.cli.failover(resp, err)
+		if x.cli.balance != nil {
+			x.cli.balance.mark(i, failed)
+		}
+
+		if !failed || j == len(order)-1 {
+			if err != nil && len(errs) > 0 {
+				err = errors.Join(append(errs, fmt.Errorf("endpoint %s: %w", addr, err))...)
+			}
+			if resp != nil {
+				x.failures, x.failAddr = errs, addr
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			err = statusError(resp)
+		}
+		errs = append(errs, fmt.Errorf("endpoint %s: %w", addr, err))
+	}
+	return nil, errors.New("http client has no endpoint")
 }
 
-// Reader sends the http request and returns a response reader
-func (x *writer) Reader() (msg.Reader, error) {
-	resp, err := x.cli.cli.Post(x.cli.addr, "application/octet-stream", &x.buf)
+// sendTo performs a single request to addr.
+func (x *ClientWriter) sendTo(ctx context.Context, addr string, p payload) (*http.Response, error) {
+	addr, err := addrMake(addr, p.path, p.query)
 	if err != nil {
-		return nil, err
+		return nil, errRequest{err}
+	}
+
+	// a new bytes.Reader makes the body replayable; an empty one results in an http.NoBody request body
+	req, err := x.cli.request(ctx, addr, bytes.NewReader(p.body), x.header, p.header)
+	if err != nil {
+		return nil, errRequest{err}
 	}
+	// always known for buffered bodies, so that they are never sent chunked, which some servers reject
+	req.ContentLength = int64(len(p.body))
 
-	if resp.StatusCode != 200 {
-		return nil, errors.New("http response status " + resp.Status)
+	if req.Body != http.NoBody {
+		// b may belong to the internal buffer, which must not return to the pool while still in use
+		req.Body = x.track(req.Body)
+		getBody := req.GetBody
+		req.GetBody = func() (stdio.ReadCloser, error) {
+			r, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return x.track(r), nil
+		}
 	}
 
-	return io.ReaderOf(resp.Body), nil
+	return x.cli.do(req, &x.attempts)
+}
+
+// errRequest wraps failures to build a request, which are neither retried nor failed over, as nothing was sent.
+type errRequest struct {
+	err error
+}
+
+func (x errRequest) Error() string {
+	return x.err.Error()
+}
+
+// track makes r hold a reference to the internal buffer, until it is closed.
+func (x *ClientWriter) track(r stdio.ReadCloser) stdio.ReadCloser {
+	x.buf.acquire()
+	return &bodyTracked{
+		ReadCloser: r,
+		buf:        x.buf,
+	}
 }
 
-func (x *writer) Write(b []byte) (int, error) {
+func (x *ClientWriter) Write(b []byte) (int, error) {
+	if x.buf == nil {
+		return 0, errClosed
+	}
 	return x.buf.Write(b)
 }
 
-type writerResp struct {
-	http.ResponseWriter
+// bodyTracked releases its buffer reference when closed.
+type bodyTracked struct {
+	stdio.ReadCloser
+	buf  *bufShared
+	once sync.Once
 }
 
-func (x writerResp) Close() error {
-	return nil
+func (x *bodyTracked) Close() error {
+	err := x.ReadCloser.Close()
+	x.once.Do(x.buf.release)
+	return err
 }
 
-// HandlerCORS wraps h to accept CORS requests from the specified origin.
-func HandlerCORS(origin string, h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodOptions {
-			header := w.Header()
-			header.Add("access-control-allow-origin", origin)
-			header.Add("access-control-allow-method", http.MethodPost)
-			header.Add("access-control-allow-headers", "content-type")
+// addrMake joins path to the path of addr, and appends the encoded query, preserving any existing query parameters.
+func addrMake(addr, path, query string) (string, error) {
+	if path == "" && query == "" {
+		return addr, nil
+	}
 
-			w.Write([]byte("OK"))
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if path != "" {
+		u = u.JoinPath(path)
+	}
+	if query != "" {
+		if u.RawQuery == "" {
+			u.RawQuery = query
 		} else {
-			w.Header().Add("access-control-allow-origin", origin)
-			h.ServeHTTP(w, r)
+			u.RawQuery += "&" + query
 		}
-	})
+	}
+	return u.String(), nil
+}
+
+// uuid returns a random (version 4) UUID.
+func uuid() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// headerCopy sets all keys from src onto dst, replacing existing values.
+// src may be nil.
+func headerCopy(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+}
+
+// ErrTimeout is the cause of exchanges aborted by an exceeded [ClientWriter.SetTimeout] duration.
+// It also matches [context.DeadlineExceeded].
+var ErrTimeout = fmt.Errorf("http exchange timeout: %w", context.DeadlineExceeded)
+
+// ErrResponseSize is returned when reading a response body that exceeds [Client.MaxResponseSize].
+var ErrResponseSize = errors.New("http response body exceeds size limit")
+
+// statusBodyMax is the maximum number of response body bytes captured by a StatusError.
+const statusBodyMax = 4096
+
+// A StatusError is returned when a response status code is not accepted by the Client.
+type StatusError struct {
+	StatusCode  int
+	Status      string
+	ContentType string        // of the response body; tells application errors apart from, say, HTML pages sent by proxies
+	Body        []byte        // response body, up to a limit
+	RetryAfter  time.Duration // delay requested through the Retry-After header, if present
+}
+
+func (x *StatusError) Error() string {
+	return "http response status " + x.Status
+}
 
+// A ClientReader is the response [msg.Reader] returned by a ClientWriter.
+type ClientReader struct {
+	r      msg.Reader
+	resp   *http.Response
+	cancel context.CancelFunc // may be nil
+	drain  bool               // read the rest of the body on Close
+
+	elapsed  time.Duration
+	attempts int
+}
+
+// Attempts returns the number of requests that were sent in order to obtain the response.
+// See [ClientWriter.Attempts].
+func (x *ClientReader) Attempts() int {
+	return x.attempts
+}
+
+// Close releases the response.
+// Small responses of known length are read to completion first, allowing the connection to be reused for other requests.
+func (x *ClientReader) Close() error {
+	if x.drain {
+		stdio.Copy(stdio.Discard, x.resp.Body)
+	}
+	err := x.r.Close()
+	if x.cancel != nil {
+		x.cancel()
+	}
+	return err
+}
+
+// ContentType returns the response content type, as sent by the server.
+func (x *ClientReader) ContentType() string {
+	return x.resp.Header.Get("content-type")
+}
+
+// Cookies returns the cookies set by the response.
+func (x *ClientReader) Cookies() []*http.Cookie {
+	return x.resp.Cookies()
+}
+
+// Elapsed returns the time it took to obtain the response.
+// See [ClientWriter.Elapsed].
+func (x *ClientReader) Elapsed() time.Duration {
+	return x.elapsed
+}
+
+// Header returns the response header.
+func (x *ClientReader) Header() http.Header {
+	return x.resp.Header
+}
+
+func (x *ClientReader) Read(b []byte) (int, error) {
+	return x.r.Read(b)
+}
+
+// Response returns the underlying response.
+// Its body must not be used directly; use the ClientReader instead.
+func (x *ClientReader) Response() *http.Response {
+	return x.resp
+}
+
+// Trailer returns the response trailer, which servers may send after the body.
+// It is only fully populated once Read has returned EOF; before that, it may only list the announced trailer keys, without values.
+func (x *ClientReader) Trailer() http.Header {
+	return x.resp.Trailer
+}
+
+// limitReader fails with ErrResponseSize if more than n bytes are available.
+type limitReader struct {
+	r io.Reader
+	n int64 // remaining bytes
+}
+
+func (x *limitReader) Close() error {
+	return x.r.Close()
+}
+
+func (x *limitReader) Read(b []byte) (int, error) {
+	if x.n < 0 {
+		return 0, ErrResponseSize
+	}
+
+	// read one byte past the limit, in order to detect an exceeding body
+	// comparing against n itself avoids overflowing n+1 for the largest limits
+	if int64(len(b)) > x.n {
+		b = b[:x.n+1]
+	}
+	n, err := x.r.Read(b)
+	x.n -= int64(n)
+	if x.n < 0 {
+		return n - 1, ErrResponseSize
+	}
+	return n, err
+}
+
+// body wraps a response body to report context cancellation explicitly.
+type body struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (x body) Close() error {
+	return x.r.Close()
+}
+
+func (x body) Read(b []byte) (int, error) {
+	n, err := x.r.Read(b)
+	if err != nil && err != io.EOF && x.ctx.Err() != nil {
+		err = fmt.Errorf("http response aborted: %w", context.Cause(x.ctx))
+	}
+	return n, err
 }
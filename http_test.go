@@ -0,0 +1,96 @@
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	stdio "io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientGzipResponse(t *testing.T) {
+	const content = "transparently decoded"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("accept-encoding"), "gzip") {
+			t.Errorf("Accept-Encoding %q does not advertise gzip", r.Header.Get("accept-encoding"))
+		}
+		w.Header().Set("content-encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(content))
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	w, err := ClientMake(srv.URL, srv.Client()).Writer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	r, err := w.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	b, err := stdio.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Fatalf("got %q, want %q", b, content)
+	}
+}
+
+func TestClientConnectionReuse(t *testing.T) {
+	const half = 8 << 10
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// small enough to be drained on Close
+		w.Header().Set("content-length", strconv.Itoa(2*half))
+		w.Write([]byte(strings.Repeat("x", half)))
+		w.(http.Flusher).Flush()
+		// outlasts the brief drain that the standard transport attempts on its own
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(strings.Repeat("x", half)))
+	}))
+	defer srv.Close()
+
+	cli := ClientMake(srv.URL, srv.Client())
+
+	var reused []bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			reused = append(reused, info.Reused)
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		w, err := cli.WriterContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := w.Reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// only read part of the body, relying on Close to drain the rest
+		if _, err := r.Read(make([]byte, 16)); err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		w.Close()
+	}
+
+	if len(reused) != 2 {
+		t.Fatalf("got %d connections, want 2", len(reused))
+	}
+	if !reused[1] {
+		t.Fatal("second exchange did not reuse the connection")
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stdio "io"
+	"net/http"
+)
+
+// ClientJSON returns a Client that exchanges JSON data, for use with [Client.JSON].
+// cli may be nil, in which case the default http client is used.
+func ClientJSON(addr string, cli *http.Client) Client {
+	x := ClientMake(addr, cli)
+	x.ContentType = "application/json"
+	x.Accept = "application/json"
+	return x
+}
+
+// JSON performs a buffered exchange, sending in encoded as JSON and decoding the response into out.
+// in may be nil, for an empty request body. out may be nil, in which case the response body is discarded.
+// The request is labeled as JSON, and asks for a JSON response unless the Client specifies another Accept value.
+// An empty response body leaves out untouched.
+//
+// Failure to decode the response results in a *DecodeError, while other errors are returned as is.
+func (x Client) JSON(ctx context.Context, in, out any) error {
+	mw, err := x.WriterContext(ctx)
+	if err != nil {
+		return err
+	}
+	w := mw.(*ClientWriter)
+	defer w.Close()
+
+	if out != nil && x.Accept == "" && x.Header.Get("accept") == "" {
+		w.Header().Set("accept", "application/json")
+	}
+	if in != nil {
+		w.Header().Set("content-type", "application/json")
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	r, err := w.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if out == nil {
+		return nil
+	}
+	b, err := stdio.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return &DecodeError{err}
+	}
+	return nil
+}
+
+// A DecodeError signals a response body that could not be decoded.
+type DecodeError struct {
+	Err error
+}
+
+func (x *DecodeError) Error() string {
+	return "http response decoding: " + x.Err.Error()
+}
+
+func (x *DecodeError) Unwrap() error {
+	return x.Err
+}
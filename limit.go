@@ -0,0 +1,218 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A RateLimit configures request rate limiting, using the token bucket algorithm.
+type RateLimit struct {
+	Rate  float64 // sustained number of requests per second
+	Burst int     // maximum number of requests allowed at once; at least 1
+
+	// Key groups requests into independently limited buckets.
+	// If nil, all requests share a single bucket.
+	// See [RateKeyIP] for limiting by client address.
+	Key func(*http.Request) string
+}
+
+// HandlerRateLimit wraps h to limit the rate of incoming requests.
+// Requests that exceed the limit are answered with 429 Too Many Requests, along with a Retry-After header.
+func HandlerRateLimit(h http.Handler, limit RateLimit) http.Handler {
+	buckets := bucketsMake(limit.Rate, float64(max(limit.Burst, 1)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if limit.Key != nil {
+			key = limit.Key(r)
+		}
+
+		if wait := buckets.take(key, time.Now()); wait > 0 {
+			w.Header().Set("retry-after", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// HandlerAllowIP wraps h to only accept requests from clients within the given CIDR ranges, answering others with 403 Forbidden.
+// Single addresses are also accepted, as one address ranges.
+// header is used as in [RateKeyIP], and may be empty.
+// It must only be set behind a proxy that appends the connecting address to it, as the last entry is trusted as is.
+//
+// Returns an error if any of the ranges is malformed.
+func HandlerAllowIP(h http.Handler, cidrs []string, header string) (http.Handler, error) {
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		var err error
+		if strings.Contains(cidr, "/") {
+			prefixes[i], err = netip.ParsePrefix(cidr)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(cidr)
+			prefixes[i] = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range %q: %w", cidr, err)
+		}
+		prefixes[i] = prefixes[i].Masked()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, err := netip.ParseAddr(clientIP(r, header))
+		if err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					h.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	}), nil
+}
+
+// RateKeyIP returns a [RateLimit.Key] function that groups requests by client IP address.
+// If header is not empty, the address is taken from its last entry when present, such as an X-Forwarded-For header appended to by a trusted proxy.
+// Earlier entries are ignored, as they are supplied by the client.
+func RateKeyIP(header string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return clientIP(r, header)
+	}
+}
+
+// clientIP returns the client IP address of r, taken from the last entry of header if present, or from the connection otherwise.
+// header may be empty.
+func clientIP(r *http.Request, header string) string {
+	if header != "" {
+		// the header may be split across multiple lines
+		if vs := r.Header.Values(header); len(vs) > 0 {
+			v := vs[len(vs)-1]
+			if i := strings.LastIndexByte(v, ','); i >= 0 {
+				v = v[i+1:]
+			}
+			return strings.TrimSpace(v)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// bucketsSweep is the number of buckets that triggers a sweep of idle ones.
+const bucketsSweep = 1024
+
+// buckets is a keyed set of token buckets, sharing the same configuration.
+type buckets struct {
+	mu    sync.Mutex
+	m     map[string]*bucket
+	rate  float64
+	burst float64
+	sweep int // bucket count that triggers the next sweep
+}
+
+func bucketsMake(rate, burst float64) *buckets {
+	return &buckets{
+		m:     make(map[string]*bucket),
+		rate:  rate,
+		burst: burst,
+		sweep: bucketsSweep,
+	}
+}
+
+// take takes a token from the bucket identified by key.
+// Returns zero on success, or the time until a token becomes available.
+func (x *buckets) take(key string, now time.Time) time.Duration {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	b, ok := x.m[key]
+	if !ok {
+		if len(x.m) >= x.sweep {
+			x.clean(now)
+		}
+		b = &bucket{
+			tokens: x.burst,
+			last:   now,
+		}
+		x.m[key] = b
+	}
+
+	return b.take(now, x.rate, x.burst)
+}
+
+// clean removes buckets that have refilled completely, as they are equivalent to new ones.
+func (x *buckets) clean(now time.Time) {
+	for key, b := range x.m {
+		if b.fill(now, x.rate, x.burst) >= x.burst {
+			delete(x.m, key)
+		}
+	}
+	x.sweep = max(bucketsSweep, 2*len(x.m))
+}
+
+// bucket is a single token bucket. It is not safe for concurrent use.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// fill adds the tokens accumulated since the last update, and returns the new amount.
+func (x *bucket) fill(now time.Time, rate, burst float64) float64 {
+	if elapsed := now.Sub(x.last); elapsed > 0 {
+		x.tokens = min(burst, x.tokens+elapsed.Seconds()*rate)
+		x.last = now
+	}
+	return x.tokens
+}
+
+// take takes a single token.
+// Returns zero on success, or the time until a token becomes available.
+func (x *bucket) take(now time.Time, rate, burst float64) time.Duration {
+	if x.fill(now, rate, burst) >= 1 {
+		x.tokens--
+		return 0
+	}
+	if rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration((1 - x.tokens) / rate * float64(time.Second))
+}
+
+// A Semaphore limits the number of concurrent operations.
+// The zero value imposes no limit. It may be shared between Clients.
+type Semaphore chan struct{}
+
+// SemaphoreMake returns a Semaphore that allows up to n concurrent operations.
+func SemaphoreMake(n int) Semaphore {
+	return make(Semaphore, max(n, 1))
+}
+
+// acquire blocks until an operation is allowed, or ctx is done.
+func (x Semaphore) acquire(ctx context.Context) error {
+	select {
+	case x <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func (x Semaphore) release() {
+	<-x
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/blitz-frost/io/msg"
+)
+
+var errLoopbackClosed = errors.New("http loopback closed")
+
+// Loopback returns a Client connected to a Handler that chains into ert, entirely in memory.
+// No network port is bound, which makes it suitable for fast and deterministic round trip tests.
+// The returned function shuts the Handler down and releases all resources.
+func Loopback(ert msg.ExchangeReaderTaker) (Client, func()) {
+	var h Handler
+	h.ReaderChain(ert)
+
+	l := &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+	srv := &http.Server{Handler: &h}
+	go srv.Serve(l)
+
+	tr := &http.Transport{
+		DialContext: l.dial,
+	}
+	stop := func() {
+		tr.CloseIdleConnections()
+		srv.Close()
+	}
+	return ClientMake("http://loopback", &http.Client{Transport: tr}), stop
+}
+
+// pipeListener is a [net.Listener] that hands out in-memory connections created by dial.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (x *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-x.conns:
+		return c, nil
+	case <-x.done:
+		return nil, errLoopbackClosed
+	}
+}
+
+func (x *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+func (x *pipeListener) Close() error {
+	x.once.Do(func() {
+		close(x.done)
+	})
+	return nil
+}
+
+func (x *pipeListener) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, s := net.Pipe()
+	select {
+	case x.conns <- s:
+		return c, nil
+	case <-x.done:
+		return nil, errLoopbackClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "loopback" }
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the latency histogram buckets.
+var latencyBounds = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Metrics accumulates request statistics. It is safe for concurrent use, and may be shared between Clients.
+// It can also collect server side statistics, through HandlerMetrics.
+//
+// The zero value is ready to use.
+type Metrics struct {
+	requests atomic.Int64
+	inFlight atomic.Int64
+	errors   atomic.Int64
+	status   [6]atomic.Int64 // indexed by status class
+	latency  [len(latencyBounds) + 1]atomic.Int64
+	duration atomic.Int64 // total latency
+}
+
+// Stats returns a snapshot of the current statistics.
+func (x *Metrics) Stats() Stats {
+	o := Stats{
+		Requests: x.requests.Load(),
+		InFlight: x.inFlight.Load(),
+		Errors:   x.errors.Load(),
+		Duration: time.Duration(x.duration.Load()),
+		Latency:  make([]Bucket, len(x.latency)),
+	}
+	for i := range x.status {
+		o.Status[i] = x.status[i].Load()
+	}
+	for i := range x.latency {
+		if i < len(latencyBounds) {
+			o.Latency[i].Max = latencyBounds[i]
+		}
+		o.Latency[i].Count = x.latency[i].Load()
+	}
+	return o
+}
+
+// record accounts for a completed request attempt.
+// code is the response status code, or 0 if the attempt failed.
+func (x *Metrics) record(code int, elapsed time.Duration) {
+	x.inFlight.Add(-1)
+	x.requests.Add(1)
+	x.duration.Add(int64(elapsed))
+
+	if code == 0 {
+		x.errors.Add(1)
+	} else if class := code / 100; class > 0 && class < len(x.status) {
+		x.status[class].Add(1)
+	}
+
+	i := 0
+	for i < len(latencyBounds) && elapsed > latencyBounds[i] {
+		i++
+	}
+	x.latency[i].Add(1)
+}
+
+// HandlerMetrics wraps h to record its requests in m, measuring the time until h returns.
+// Requests are counted as in flight until then.
+func HandlerMetrics(h http.Handler, m *Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Add(1)
+		rec := &recorder{ResponseWriter: w}
+		start := time.Now()
+		returned := false
+		defer func() {
+			code := rec.Status()
+			if !returned && rec.status == 0 {
+				// panicked before responding, which results in an error response or an aborted connection
+				code = http.StatusInternalServerError
+			}
+			m.record(code, time.Since(start))
+		}()
+
+		h.ServeHTTP(rec, r)
+		returned = true
+	})
+}
+
+// A Bucket is a latency histogram bucket.
+type Bucket struct {
+	Max   time.Duration // inclusive upper bound; zero for the last, unbounded bucket
+	Count int64         // number of requests that fall in this bucket, but not the previous one
+}
+
+// Stats is a snapshot of request statistics.
+type Stats struct {
+	Requests int64         // completed requests
+	InFlight int64         // requests awaiting a response
+	Errors   int64         // requests that failed without a response
+	Status   [6]int64      // responses by status class; for example, Status[5] counts 5xx responses
+	Latency  []Bucket      // time to response histogram, in increasing order
+	Duration time.Duration // total time to response of completed requests
+}
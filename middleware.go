@@ -0,0 +1,436 @@
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HandlerLogging wraps h to log every request through logger, once handled.
+// logger may be nil, in which case the default logger is used.
+func HandlerLogging(h http.Handler, logger *slog.Logger) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &recorder{ResponseWriter: w}
+		start := time.Now()
+		h.ServeHTTP(rec, r)
+
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("remote", r.RemoteAddr),
+			slog.Int("status", rec.Status()),
+			slog.Int64("size", rec.size),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// HandlerRecover wraps h to recover from panics, responding with 500 Internal Server Error if nothing was written yet.
+// onPanic is called with the recovered value; it may be nil.
+//
+// As with [Handler], [http.ErrAbortHandler] panics are left to the server, which aborts the response silently.
+func HandlerRecover(h http.Handler, onPanic func(any)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &recorder{ResponseWriter: w}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			if rec.status == 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			if onPanic != nil {
+				onPanic(v)
+			}
+		}()
+
+		h.ServeHTTP(rec, r)
+	})
+}
+
+// recorder records the status code and body size of a response.
+type recorder struct {
+	http.ResponseWriter
+	status int // zero until the headers are sent
+	size   int64
+}
+
+func (x *recorder) Flush() {
+	x.FlushError()
+}
+
+func (x *recorder) FlushError() error {
+	if x.status == 0 {
+		x.status = http.StatusOK
+	}
+	return http.NewResponseController(x.ResponseWriter).Flush()
+}
+
+// Status returns the response status code, assuming an implicit 200 OK if nothing was written.
+func (x *recorder) Status() int {
+	if x.status == 0 {
+		return http.StatusOK
+	}
+	return x.status
+}
+
+func (x *recorder) Unwrap() http.ResponseWriter {
+	return x.ResponseWriter
+}
+
+func (x *recorder) Write(b []byte) (int, error) {
+	if x.status == 0 {
+		x.status = http.StatusOK
+	}
+	n, err := x.ResponseWriter.Write(b)
+	x.size += int64(n)
+	return n, err
+}
+
+func (x *recorder) WriteHeader(code int) {
+	// informational responses may precede the final one
+	if x.status == 0 && code >= 200 {
+		x.status = code
+	}
+	x.ResponseWriter.WriteHeader(code)
+}
+
+// HandlerGzip wraps h to transparently handle gzip content encoding.
+// Request bodies with a gzip content encoding are decompressed before reaching h.
+// Responses are compressed if the client accepts gzip, unless they are already encoded, partial, or their content type is already compressed.
+func HandlerGzip(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(strings.TrimSpace(r.Header.Get("content-encoding")), "gzip") {
+			body, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip request body", http.StatusBadRequest)
+				return
+			}
+			defer body.Close()
+
+			r.Body = body
+			r.Header.Del("content-encoding")
+			r.Header.Del("content-length")
+			r.ContentLength = -1
+		}
+
+		// the response may differ for other clients, which caches must know about
+		w.Header().Add("vary", "Accept-Encoding")
+
+		if r.Method == http.MethodHead || !accepts(r.Header.Get("accept-encoding"), "gzip") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+		h.ServeHTTP(gw, r)
+	})
+}
+
+// accepts reports whether an Accept-Encoding header value allows the given encoding.
+// An explicit entry for the encoding takes precedence over a "*" wildcard.
+func accepts(header, encoding string) bool {
+	wildcard := false // whether "*" allows the encoding, in case there is no explicit entry
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		explicit := strings.EqualFold(name, encoding)
+		if !explicit && name != "*" {
+			continue
+		}
+
+		ok := true
+		q := strings.TrimSpace(params)
+		if v, found := strings.CutPrefix(q, "q="); found {
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f == 0 {
+				ok = false
+			}
+		}
+		if explicit {
+			return ok
+		}
+		wildcard = ok
+	}
+	return wildcard
+}
+
+// compressedTypes lists content types that do not benefit from further compression.
+// Entries ending in "/" match whole type families.
+var compressedTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+}
+
+// gzipWriter compresses a response, deciding whether to do so when the first data is written.
+// The response headers are delayed until then, in order to allow content type detection.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer // nil if not compressing
+	code    int          // pending status code; zero if WriteHeader was not called
+	started bool         // headers were sent
+}
+
+// close sends any pending headers and terminates the compressed stream, if any.
+func (x *gzipWriter) close() {
+	if !x.started && x.code != 0 {
+		x.start(nil)
+	}
+	if x.gz != nil {
+		x.gz.Close()
+	}
+}
+
+// start sets up compression according to the response headers, and sends them.
+// b is the first written data, used for content type detection if needed.
+func (x *gzipWriter) start(b []byte) {
+	x.started = true
+	code := x.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	defer x.ResponseWriter.WriteHeader(code)
+
+	header := x.Header()
+	if code == http.StatusNoContent || code == http.StatusNotModified || header.Get("content-encoding") != "" {
+		return
+	}
+	// Content-Range refers to the unencoded content
+	if code == http.StatusPartialContent || header.Get("content-range") != "" {
+		return
+	}
+
+	contentType := header.Get("content-type")
+	if contentType == "" {
+		if len(b) == 0 {
+			// nothing to detect from, and likely nothing to compress
+			return
+		}
+		// the server would otherwise sniff the compressed data
+		contentType = http.DetectContentType(b)
+		header.Set("content-type", contentType)
+	}
+	for _, t := range compressedTypes {
+		if strings.HasPrefix(contentType, t) {
+			return
+		}
+	}
+
+	header.Set("content-encoding", "gzip")
+	header.Del("content-length")
+	x.gz = gzip.NewWriter(x.ResponseWriter)
+}
+
+func (x *gzipWriter) Flush() {
+	x.FlushError()
+}
+
+func (x *gzipWriter) FlushError() error {
+	if !x.started {
+		x.start(nil)
+	}
+	if x.gz != nil {
+		if err := x.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(x.ResponseWriter).Flush()
+}
+
+func (x *gzipWriter) Unwrap() http.ResponseWriter {
+	return x.ResponseWriter
+}
+
+func (x *gzipWriter) Write(b []byte) (int, error) {
+	if !x.started {
+		x.start(b)
+	}
+	if x.gz == nil {
+		return x.ResponseWriter.Write(b)
+	}
+	return x.gz.Write(b)
+}
+
+func (x *gzipWriter) WriteHeader(code int) {
+	if code < 200 {
+		// informational responses are passed through
+		x.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if x.started || x.code != 0 {
+		return
+	}
+	x.code = code
+}
+
+// HandlerTimeout wraps h to limit its processing time to d.
+// The request passed to h carries a context with the corresponding deadline, which h should honor.
+//
+// If h does not finish in time, and has not written anything yet, the request is answered with 503 Service Unavailable, and any further writes by h fail with [http.ErrHandlerTimeout].
+// Otherwise, the response is left to h, which is still waited for.
+func HandlerTimeout(h http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		tw := &timeoutWriter{
+			w:      w,
+			header: make(http.Header),
+		}
+		done := make(chan any, 1) // carries panic values, if any
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			h.ServeHTTP(tw, r.WithContext(ctx))
+		}()
+
+		select {
+		case v := <-done:
+			if v != nil {
+				panic(v)
+			}
+			return
+		case <-ctx.Done():
+		}
+
+		tw.mu.Lock()
+		if !tw.written {
+			tw.timedOut = true
+			tw.mu.Unlock()
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		tw.mu.Unlock()
+
+		if v := <-done; v != nil {
+			panic(v)
+		}
+	})
+}
+
+// timeoutWriter guards a ResponseWriter that may be abandoned by a timed out handler.
+// It uses its own header map, so that the handler cannot interfere with the timeout response.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu       sync.Mutex
+	written  bool // headers were sent
+	timedOut bool
+}
+
+func (x *timeoutWriter) Flush() {
+	x.FlushError()
+}
+
+func (x *timeoutWriter) FlushError() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	x.start(http.StatusOK)
+	return http.NewResponseController(x.w).Flush()
+}
+
+func (x *timeoutWriter) Header() http.Header {
+	return x.header
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying ResponseWriter, until the handler times out.
+func (x *timeoutWriter) Unwrap() http.ResponseWriter {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.timedOut {
+		return nil
+	}
+	return x.w
+}
+
+func (x *timeoutWriter) Write(b []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	x.start(http.StatusOK)
+	return x.w.Write(b)
+}
+
+func (x *timeoutWriter) WriteHeader(code int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.timedOut {
+		return
+	}
+	if code < 200 {
+		// informational responses do not commit the response
+		headerCopy(x.w.Header(), x.header)
+		x.w.WriteHeader(code)
+		return
+	}
+	x.start(code)
+}
+
+// start sends the response headers, if not already sent.
+// The caller must hold the lock.
+func (x *timeoutWriter) start(code int) {
+	if x.written {
+		return
+	}
+	x.written = true
+	headerCopy(x.w.Header(), x.header)
+	x.w.WriteHeader(code)
+}
+
+// HandlerHealth returns a health check Handler, which answers GET and HEAD requests with 200 OK if check succeeds.
+// Otherwise, it answers with 503 Service Unavailable, along with the error message.
+// check may be nil, for a plain liveness probe.
+func HandlerHealth(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("cache-control", "no-store")
+		if check != nil {
+			if err := check(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("content-type", "text/plain; charset=utf-8")
+		w.Write([]byte("OK"))
+	})
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	stdio "io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/blitz-frost/io/msg"
+)
+
+// WriterMultipart returns a Writer that uploads a multipart/form-data request body, made up of form fields and files.
+// The body is streamed as parts are added, so file contents need not fit in memory.
+// As with WriterStream, the Client Retry configuration is ignored.
+func (x Client) WriterMultipart(ctx context.Context) *ClientMultipartWriter {
+	w := &ClientStreamWriter{
+		cli: x,
+		ctx: ctx,
+	}
+	mw := multipart.NewWriter(w)
+	w.Header().Set("content-type", mw.FormDataContentType())
+	return &ClientMultipartWriter{
+		w:  w,
+		mw: mw,
+	}
+}
+
+// A ClientMultipartWriter builds a multipart/form-data request body.
+// Parts are sent in the order they are added.
+type ClientMultipartWriter struct {
+	w  *ClientStreamWriter
+	mw *multipart.Writer
+}
+
+// Close aborts the exchange, if Reader has not been called yet.
+func (x *ClientMultipartWriter) Close() error {
+	return x.w.Close()
+}
+
+// Field adds a form field.
+func (x *ClientMultipartWriter) Field(name, value string) error {
+	return x.mw.WriteField(name, value)
+}
+
+// File adds a file part, copying its contents from r.
+func (x *ClientMultipartWriter) File(field, filename string, r stdio.Reader) error {
+	w, err := x.mw.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	_, err = stdio.Copy(w, r)
+	return err
+}
+
+// Header returns the header map that will be sent with this exchange's request, on top of the Client headers.
+// Modifications must be made before adding the first part.
+func (x *ClientMultipartWriter) Header() http.Header {
+	return x.w.Header()
+}
+
+// Part adds a part with an arbitrary header, returning a Writer for its contents.
+// The Writer is valid until the next part is added, or Reader is called.
+func (x *ClientMultipartWriter) Part(header textproto.MIMEHeader) (stdio.Writer, error) {
+	return x.mw.CreatePart(header)
+}
+
+// Reader terminates the request body and waits for the response.
+//
+// The returned value is a *ClientReader.
+func (x *ClientMultipartWriter) Reader() (msg.Reader, error) {
+	if !x.w.read {
+		// writes the closing boundary
+		// failure can only stem from the request itself, which the stream Reader reports
+		x.mw.Close()
+	}
+	return x.w.Reader()
+}
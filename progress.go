@@ -0,0 +1,106 @@
+package http
+
+import (
+	stdio "io"
+	"sync"
+	"sync/atomic"
+)
+
+// progress reports transfer progress to a callback.
+// The callback runs on its own goroutine, so that it cannot hold up the transfer. Updates that arrive while it is busy are merged.
+type progress struct {
+	fn    func(n, total int64)
+	total int64
+	n     atomic.Int64
+	note  chan struct{} // signals pending updates
+	done  chan struct{}
+	once  sync.Once
+}
+
+func progressMake(fn func(n, total int64), total int64) *progress {
+	x := &progress{
+		fn:    fn,
+		total: total,
+		note:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go x.run()
+	return x
+}
+
+func (x *progress) add(n int) {
+	if n == 0 {
+		return
+	}
+	x.n.Add(int64(n))
+	select {
+	case x.note <- struct{}{}:
+	default:
+	}
+}
+
+func (x *progress) run() {
+	for {
+		select {
+		case <-x.note:
+			x.fn(x.n.Load(), x.total)
+		case <-x.done:
+			select {
+			case <-x.note:
+				x.fn(x.n.Load(), x.total)
+			default:
+			}
+			return
+		}
+	}
+}
+
+// stop ends reporting, after delivering any pending update.
+func (x *progress) stop() {
+	x.once.Do(func() {
+		close(x.done)
+	})
+}
+
+// progressReader counts the bytes read through it.
+type progressReader struct {
+	r stdio.ReadCloser
+	p *progress
+}
+
+func (x *progressReader) Close() error {
+	err := x.r.Close()
+	x.p.stop()
+	return err
+}
+
+func (x *progressReader) Read(b []byte) (int, error) {
+	n, err := x.r.Read(b)
+	x.p.add(n)
+	if err != nil {
+		x.p.stop()
+	}
+	return n, err
+}
+
+// downloadProgress wraps a response body to report its progress.
+// contentLength is that of the response, -1 meaning unknown.
+func (x Client) downloadProgress(r stdio.ReadCloser, contentLength int64) stdio.ReadCloser {
+	return &progressReader{
+		r: r,
+		p: progressMake(x.DownloadProgress, contentLength),
+	}
+}
+
+// uploadProgress wraps a request body to report its progress.
+// contentLength is that of the request, 0 meaning unknown.
+func (x Client) uploadProgress(r stdio.ReadCloser, contentLength int64) stdio.ReadCloser {
+	total := contentLength
+	if total == 0 {
+		total = -1
+	}
+	return &progressReader{
+		r: r,
+		p: progressMake(x.UploadProgress, total),
+	}
+}
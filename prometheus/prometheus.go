@@ -0,0 +1,114 @@
+// Package prometheus exposes [http.Metrics] in the Prometheus text exposition format.
+//
+// It is self-contained, so that using it does not pull the Prometheus client libraries into the build.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	nethttp "net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/blitz-frost/http"
+)
+
+// A Registry holds named Metrics, and serves them to Prometheus scrapers as an [net/http.Handler].
+// Each Metrics is labeled by its name, under the "source" label.
+//
+// Exported metrics:
+//
+//	http_requests_total{source, class}           counter, by status class ("2xx", etc.), or "error" for failed requests
+//	http_requests_in_flight{source}              gauge
+//	http_request_duration_seconds{source, le}    histogram
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]*http.Metrics
+}
+
+func RegistryMake() *Registry {
+	return &Registry{
+		metrics: make(map[string]*http.Metrics),
+	}
+}
+
+// Register adds m to the Registry under name, replacing any previous Metrics with the same name.
+func (x *Registry) Register(name string, m *http.Metrics) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.metrics[name] = m
+}
+
+func (x *Registry) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var buf bytes.Buffer
+	x.write(&buf)
+
+	w.Header().Set("content-type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// stat is a named Stats snapshot.
+type stat struct {
+	source string // escaped
+	stats  http.Stats
+}
+
+// snapshot returns the current Stats of all registered Metrics, in name order.
+func (x *Registry) snapshot() []stat {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	o := make([]stat, 0, len(x.metrics))
+	for name, m := range x.metrics {
+		o = append(o, stat{labelEscape(name), m.Stats()})
+	}
+	slices.SortFunc(o, func(a, b stat) int {
+		return strings.Compare(a.source, b.source)
+	})
+	return o
+}
+
+func (x *Registry) write(buf *bytes.Buffer) {
+	stats := x.snapshot()
+
+	buf.WriteString("# HELP http_requests_total Completed requests, by response status class.\n")
+	buf.WriteString("# TYPE http_requests_total counter\n")
+	for _, s := range stats {
+		for class := 1; class < len(s.stats.Status); class++ {
+			fmt.Fprintf(buf, "http_requests_total{source=\"%s\",class=\"%dxx\"} %d\n", s.source, class, s.stats.Status[class])
+		}
+		fmt.Fprintf(buf, "http_requests_total{source=\"%s\",class=\"error\"} %d\n", s.source, s.stats.Errors)
+	}
+
+	buf.WriteString("# HELP http_requests_in_flight Requests awaiting a response.\n")
+	buf.WriteString("# TYPE http_requests_in_flight gauge\n")
+	for _, s := range stats {
+		fmt.Fprintf(buf, "http_requests_in_flight{source=\"%s\"} %d\n", s.source, s.stats.InFlight)
+	}
+
+	buf.WriteString("# HELP http_request_duration_seconds Time to response.\n")
+	buf.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, s := range stats {
+		var n int64
+		for _, b := range s.stats.Latency {
+			n += b.Count
+			le := "+Inf"
+			if b.Max > 0 {
+				le = strconv.FormatFloat(b.Max.Seconds(), 'g', -1, 64)
+			}
+			fmt.Fprintf(buf, "http_request_duration_seconds_bucket{source=\"%s\",le=\"%s\"} %d\n", s.source, le, n)
+		}
+		fmt.Fprintf(buf, "http_request_duration_seconds_sum{source=\"%s\"} %s\n", s.source, strconv.FormatFloat(s.stats.Duration.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(buf, "http_request_duration_seconds_count{source=\"%s\"} %d\n", s.source, n)
+	}
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelEscape escapes a label value for the text format.
+func labelEscape(s string) string {
+	return labelReplacer.Replace(s)
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetRange requests only the bytes from start to end of the response, inclusive, allowing interrupted downloads to be resumed.
+// A negative end requests everything from start onwards.
+//
+// Servers that honor the request answer with 206 Partial Content, in which case [ClientReader.ContentRange] reports the range actually sent.
+// Servers may also ignore it and send the full response.
+func (x *ClientWriter) SetRange(start, end int64) {
+	v := "bytes=" + strconv.FormatInt(start, 10) + "-"
+	if end >= 0 {
+		v += strconv.FormatInt(end, 10)
+	}
+	x.Header().Set("range", v)
+}
+
+// ContentRange returns the byte range of the complete resource that the response body covers, as per its Content-Range header.
+// size is -1 if the server does not know the complete length.
+// ok is false if the response is not partial.
+func (x *ClientReader) ContentRange() (start, end, size int64, ok bool) {
+	v, ok := strings.CutPrefix(x.resp.Header.Get("content-range"), "bytes ")
+	if !ok {
+		return 0, 0, 0, false
+	}
+	rng, total, ok := strings.Cut(v, "/")
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	size = -1
+	if total != "*" {
+		n, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		size = n
+	}
+
+	if _, err := fmt.Sscanf(rng, "%d-%d", &start, &end); err != nil {
+		// "*" ranges accompany 416 responses
+		return 0, 0, 0, false
+	}
+	return start, end, size, true
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retry configures how a Client retries failed requests.
+// The zero value disables retrying.
+type Retry struct {
+	Max        int           // maximum number of retries
+	Backoff    time.Duration // delay before the first retry; doubled for each subsequent one
+	BackoffMax time.Duration // upper bound for the delay, if positive
+	Jitter     Jitter        // randomizes delays, so that clients failing together do not retry together
+
+	// Rand returns a uniformly distributed random number in [0, n), for use by Jitter.
+	// Setting it to the Int64N method of a seeded [rand.Rand] makes delays deterministic, as long as it is not shared between concurrent exchanges.
+	// If nil, the math/rand/v2 top level function is used.
+	Rand func(n int64) int64
+
+	// AfterMax caps delays requested by servers through the Retry-After header, if positive.
+	// A requested delay is only used if longer than the current backoff delay.
+	AfterMax time.Duration
+
+	// Should reports whether a request attempt should be retried.
+	// Exactly one of resp and err is non-nil.
+	// If nil, transport errors and 502, 503 and 504 responses are retried.
+	// Errors building the request, such as Auth failures, are never retried.
+	Should func(resp *http.Response, err error) bool
+
+	// Budget caps the aggregate retry rate, across all exchanges that share it.
+	// Once exhausted, failed requests are not retried until it refills. May be nil.
+	Budget *RetryBudget
+}
+
+// A RetryBudget is a token bucket that limits the rate of retries, in order to avoid amplifying load during outages.
+// It is safe for concurrent use, and may be shared between Clients.
+type RetryBudget struct {
+	mu    sync.Mutex
+	b     bucket
+	rate  float64
+	burst float64
+}
+
+// RetryBudgetMake returns a RetryBudget that allows rate retries per second on average, with bursts of up to burst retries.
+func RetryBudgetMake(rate float64, burst int) *RetryBudget {
+	return &RetryBudget{
+		b: bucket{
+			tokens: float64(burst),
+			last:   time.Now(),
+		},
+		rate:  rate,
+		burst: float64(burst),
+	}
+}
+
+// take reports whether a retry is allowed, consuming a token if so.
+func (x *RetryBudget) take() bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.b.take(time.Now(), x.rate, x.burst) == 0
+}
+
+// Jitter selects a backoff randomization strategy.
+type Jitter int
+
+const (
+	JitterNone         Jitter = iota // plain exponential backoff
+	JitterFull                       // random delay between 0 and the exponential backoff delay
+	JitterDecorrelated               // random delay between Backoff and three times the previous delay
+)
+
+// rand returns a random duration in [0, d].
+func (x Retry) rand(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	f := x.Rand
+	if f == nil {
+		f = rand.Int64N
+	}
+	return time.Duration(f(int64(d) + 1))
+}
+
+// wait returns the delay to apply for the backoff state d.
+func (x Retry) wait(d time.Duration) time.Duration {
+	if x.Jitter == JitterFull {
+		return x.rand(d)
+	}
+	return d
+}
+
+// after returns the delay before retrying after resp, starting from the backoff delay d.
+// resp may be nil.
+func (x Retry) after(resp *http.Response, d time.Duration) time.Duration {
+	if resp == nil {
+		return d
+	}
+	after, ok := retryAfter(resp.Header)
+	if !ok {
+		return d
+	}
+	if x.AfterMax > 0 && after > x.AfterMax {
+		after = x.AfterMax
+	}
+	return max(d, after)
+}
+
+// next returns the backoff state that follows d.
+func (x Retry) next(d time.Duration) time.Duration {
+	if x.Jitter == JitterDecorrelated {
+		d = x.Backoff + x.rand(max(3*d-x.Backoff, 0))
+	} else {
+		d *= 2
+	}
+	if x.BackoffMax > 0 && d > x.BackoffMax {
+		d = x.BackoffMax
+	}
+	return d
+}
+
+func (x Retry) should(resp *http.Response, err error) bool {
+	if x.Should != nil {
+		return x.Should(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d to pass, or returns early with the context cause.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return context.Cause(ctx)
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// retryAfter parses the Retry-After header, in either its seconds or HTTP date form.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	v := header.Get("retry-after")
+	if v == "" {
+		return 0, false
+	}
+
+	if n, err := strconv.Atoi(v); err == nil {
+		if n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdio "io"
+	"net/http"
+
+	"github.com/blitz-frost/io/msg"
+)
+
+// errAborted is used to abort a streamed request body.
+var errAborted = errors.New("http request aborted")
+
+// WriterStream returns an exchange Writer that streams written data as the request body, rather than buffering it.
+// The request is sent on the first Write, or on the Reader call if nothing was written.
+// Since the request body is not replayable, the Client Retry configuration is ignored.
+//
+// The returned value is a *ClientStreamWriter.
+func (x Client) WriterStream(ctx context.Context) (msg.ExchangeWriter, error) {
+	return &ClientStreamWriter{
+		cli: x,
+		ctx: ctx,
+	}, nil
+}
+
+// WriterDuplex returns an exchange Writer for full-duplex exchanges, where the request body may still be written after Reader returns.
+// Otherwise, it behaves like the Writer returned by WriterStream.
+//
+// Reader returns as soon as the response headers arrive, without terminating the request body.
+// Servers that wait for request data before responding will therefore block it, so writing must happen concurrently in such cases.
+// The request body is terminated by closing the Writer after Reader returns. Closing the response Reader aborts the whole exchange.
+//
+// Each Write is flushed to the network promptly, rather than held back until more data accumulates, although Write may return before the data is actually sent.
+// The server must support full-duplex HTTP, such as HTTP/2 servers, or HTTP/1 Go servers using [http.ResponseController.EnableFullDuplex].
+//
+// The returned value is a *ClientStreamWriter.
+func (x Client) WriterDuplex(ctx context.Context) (msg.ExchangeWriter, error) {
+	return &ClientStreamWriter{
+		cli:    x,
+		ctx:    ctx,
+		duplex: true,
+	}, nil
+}
+
+// A ClientStreamWriter is a [msg.ExchangeWriter] that streams the request body while it is being written.
+//
+// If the request fails, or the server rejects it, before the body is complete, pending and subsequent Write calls return an error.
+type ClientStreamWriter struct {
+	cli    Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	header http.Header
+	duplex bool
+	read   bool // Reader was called
+
+	pw   *stdio.PipeWriter
+	done chan struct{} // closed when the response or request error is available
+	resp *http.Response
+	err  error
+}
+
+// Close aborts the exchange, if Reader has not been called yet.
+// Otherwise, it terminates the request body in duplex mode, and is a NoOp in normal mode.
+func (x *ClientStreamWriter) Close() error {
+	if x.pw == nil {
+		return nil
+	}
+	if x.read {
+		// NoOp if already closed
+		return x.pw.Close()
+	}
+
+	x.pw.CloseWithError(errAborted)
+	x.cancel()
+	<-x.done
+	if x.resp != nil {
+		x.resp.Body.Close()
+	}
+	x.read = true
+	return nil
+}
+
+// Header returns the header map that will be sent with this exchange's request, on top of the Client headers.
+// Modifications must be made before the first Write.
+func (x *ClientStreamWriter) Header() http.Header {
+	if x.header == nil {
+		x.header = make(http.Header)
+	}
+	return x.header
+}
+
+// Reader terminates the request body, unless in duplex mode, and waits for the response.
+//
+// The returned value is a *ClientReader.
+func (x *ClientStreamWriter) Reader() (msg.Reader, error) {
+	if x.read {
+		return nil, errClosed
+	}
+	if x.pw == nil {
+		x.start()
+	}
+
+	if !x.duplex {
+		x.pw.Close()
+	}
+	<-x.done
+
+	x.read = true
+	if x.err != nil {
+		x.cancel()
+		return nil, x.err
+	}
+	return x.cli.reader(x.ctx, x.resp, x.cancel)
+}
+
+func (x *ClientStreamWriter) Write(b []byte) (int, error) {
+	if x.pw == nil {
+		x.start()
+	}
+	return x.pw.Write(b)
+}
+
+// start launches the request in the background.
+func (x *ClientStreamWriter) start() {
+	x.ctx, x.cancel = context.WithCancel(x.ctx)
+
+	pr, pw := stdio.Pipe()
+	x.pw = pw
+	x.done = make(chan struct{})
+
+	go func() {
+		defer close(x.done)
+
+		var (
+			req *http.Request
+			err = errors.New("http client has no endpoint")
+		)
+		if len(x.cli.addrs) > 0 {
+			req, err = x.cli.request(x.ctx, x.cli.addrs[0], pr, x.header)
+		}
+		if err != nil {
+			x.err = err
+			pr.CloseWithError(err)
+			return
+		}
+
+		x.resp, x.err = x.cli.do(req, nil)
+
+		// unblock the write side if the exchange cannot succeed anymore
+		if x.err != nil {
+			pr.CloseWithError(x.err)
+		} else if !x.cli.statusAccept(x.resp.StatusCode) {
+			pr.CloseWithError(errors.New("http response status " + x.resp.Status))
+		}
+	}()
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TransportConfig holds commonly tuned transport parameters.
+// Zero values leave the corresponding [http.DefaultTransport] settings in place.
+type TransportConfig struct {
+	MaxIdleConns        int           // maximum idle connections, across all hosts
+	MaxIdleConnsPerHost int           // maximum idle connections per host
+	MaxConnsPerHost     int           // maximum connections per host, including active ones
+	IdleConnTimeout     time.Duration // how long idle connections are kept
+	Timeout             time.Duration // overall request time limit, including reading the response
+	DisableKeepAlives   bool          // use each connection for a single request
+
+	// ExpectContinueTimeout is how long to wait for the server's go-ahead on requests with an "Expect: 100-continue" header,
+	// before sending the body anyway.
+	ExpectContinueTimeout time.Duration
+
+	// Proxy selects the proxy used for each request, as per [http.Transport.Proxy].
+	// If nil, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// DialContext creates the underlying connections.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	TLS *tls.Config // TLS client configuration
+}
+
+// HTTPClient returns a new http client, configured according to x.
+func (x TransportConfig) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: x.Transport(),
+		Timeout:   x.Timeout,
+	}
+}
+
+// Transport returns a new transport, configured according to x.
+func (x TransportConfig) Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if x.MaxIdleConns > 0 {
+		t.MaxIdleConns = x.MaxIdleConns
+	}
+	if x.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = x.MaxIdleConnsPerHost
+	}
+	if x.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = x.MaxConnsPerHost
+	}
+	if x.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = x.IdleConnTimeout
+	}
+	t.DisableKeepAlives = x.DisableKeepAlives
+	if x.ExpectContinueTimeout > 0 {
+		t.ExpectContinueTimeout = x.ExpectContinueTimeout
+	}
+	if x.Proxy != nil {
+		t.Proxy = x.Proxy
+	}
+	if x.DialContext != nil {
+		t.DialContext = x.DialContext
+	}
+	if x.TLS != nil {
+		t.TLSClientConfig = x.TLS
+	}
+	return t
+}
+
+// ClientTransport returns a Client that uses a dedicated http client, configured according to cfg.
+func ClientTransport(addr string, cfg TransportConfig) Client {
+	return ClientMake(addr, cfg.HTTPClient())
+}
+
+// ClientProxy returns a Client that sends its requests through the proxy at proxyURL, such as "http://proxy.example:3128".
+// Credentials may be included in the URL.
+// An empty proxyURL uses the proxy configured through the environment, like the default http client.
+func ClientProxy(addr, proxyURL string) (Client, error) {
+	var cfg TransportConfig
+	if proxyURL == "" {
+		cfg.Proxy = http.ProxyFromEnvironment
+	} else {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return Client{}, err
+		}
+		cfg.Proxy = http.ProxyURL(u)
+	}
+	return ClientTransport(addr, cfg), nil
+}
+
+// ClientUnix returns a Client that reaches a server listening on the Unix domain socket at socketPath.
+// Requests are addressed to urlPath, which may also contain a query, on a placeholder host.
+func ClientUnix(socketPath, urlPath string) Client {
+	var d net.Dialer
+	cfg := TransportConfig{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	return ClientTransport("http://unix"+urlPath, cfg)
+}
+
+// ClientTLS returns a Client that presents cert to servers, for mutual TLS authentication.
+// roots holds the certificate authorities used to verify servers. If nil, the system pool is used.
+func ClientTLS(addr string, cert tls.Certificate, roots *x509.CertPool) Client {
+	return ClientTransport(addr, TransportConfig{
+		TLS: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      roots,
+		},
+	})
+}
+
+// ClientTLSFiles is like ClientTLS, but loads the client certificate from a pair of PEM encoded files.
+func ClientTLSFiles(addr, certFile, keyFile string, roots *x509.CertPool) (Client, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return Client{}, err
+	}
+	return ClientTLS(addr, cert, roots), nil
+}
+
+// ClientWithRoundTripper returns a Client whose requests are handled by rt, without necessarily involving a network.
+// This is mainly useful for tests, which can substitute canned responses for a real server:
+//
+//	cli := http.ClientWithRoundTripper("http://test", http.RoundTripFunc(func(r *nethttp.Request) (*nethttp.Response, error) {
+//		if r.Body != nil {
+//			r.Body.Close()
+//		}
+//		return &nethttp.Response{
+//			StatusCode: nethttp.StatusOK,
+//			Header:     make(nethttp.Header),
+//			Body:       io.NopCloser(strings.NewReader("canned")),
+//			Request:    r,
+//		}, nil
+//	}))
+//
+// As with any RoundTripper, rt should close the request body once done with it.
+func ClientWithRoundTripper(addr string, rt http.RoundTripper) Client {
+	return ClientMake(addr, &http.Client{Transport: rt})
+}
+
+// RoundTripFunc adapts a function to the [http.RoundTripper] interface.
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+func (x RoundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return x(r)
+}